@@ -25,11 +25,25 @@ var goArchiver_libNamePrefix string
 // The Go linker (5l, 6l, 8l, gccgo)
 var goLinker_name string
 
-// The directory where to put/find installed libraries
-var libInstallRoot string = path.Join(runtime.GOROOT(), "pkg", runtime.GOOS+"_"+runtime.GOARCH)
+// The root of the Go installation (-goroot, $GOROOT, or the GOROOT of GOAM's own build)
+var goRoot string
+
+// The directory where to put/find installed libraries ($GOAM_LIB_ROOT, or 'goRootLibDir')
+var libInstallRoot string
+
+// The directory containing the libraries of the Go installation
+var goRootLibDir string
 
 // The directory where to put remote packages
-var remotePkgInstallRoot string = path.Join(runtime.GOROOT(), "src", "pkg")
+var remotePkgInstallRoot string
+
+// The directory containing the local copies of remote packages (in "src")
+// and installed libraries (in "pkg/GOOS_GOARCH"), or an empty string.
+// Set by the option -depsroot or by 'DepsRoot'.
+var depsRoot string
+
+// Whether calls to 'DepsRoot' are ignored
+var depsRootFixed = false
 
 // The directory where to put executables
 var exeInstallDir string
@@ -74,14 +88,89 @@ func initArch() {
 		goLinker_name = "gccgo"
 	}
 
-	exeInstallDir = os.Getenv("GOBIN")
+	goRoot = *flag_goroot
+	if len(goRoot) == 0 {
+		goRoot = os.Getenv("GOROOT")
+	}
+	if len(goRoot) == 0 {
+		goRoot = runtime.GOROOT()
+	}
+
+	goRootLibDir = path.Join(goRoot, "pkg", runtime.GOOS+"_"+runtime.GOARCH)
+	libInstallRoot = os.Getenv("GOAM_LIB_ROOT")
+	if len(libInstallRoot) == 0 {
+		libInstallRoot = goRootLibDir
+	}
+	remotePkgInstallRoot = path.Join(goRoot, "src", "pkg")
+
+	exeInstallDir = *flag_gobin
+	if len(exeInstallDir) == 0 {
+		exeInstallDir = os.Getenv("GOBIN")
+	}
 	if len(exeInstallDir) == 0 {
-		exeInstallDir = path.Join(runtime.GOROOT(), "bin")
+		exeInstallDir = path.Join(goRoot, "bin")
 	}
 
 	goCompiler_exe = &Executable{name: goCompiler_name}
 	goArchiver_exe = &Executable{name: goArchiver_name}
 	goLinker_exe = &Executable{name: goLinker_name}
+
+	// The option -depsroot takes precedence over 'DepsRoot'
+	if len(*flag_depsroot) != 0 {
+		err := setDepsRoot(*flag_depsroot)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		depsRootFixed = true
+	}
+}
+
+// Makes 'dir' the directory where remote packages are cloned and libraries are installed,
+// instead of the Go installation. $GOAM_LIB_ROOT still takes precedence for libraries.
+func setDepsRoot(dir string) error {
+	if !path.IsAbs(dir) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		dir = path.Join(cwd, dir)
+	}
+	dir = path.Clean(dir)
+
+	if *flag_debug {
+		println("deps root:", dir)
+	}
+
+	depsRoot = dir
+	remotePkgInstallRoot = path.Join(dir, "src")
+	if len(os.Getenv("GOAM_LIB_ROOT")) == 0 {
+		libInstallRoot = path.Join(dir, "pkg", runtime.GOOS+"_"+runtime.GOARCH)
+	}
+
+	return nil
+}
+
+// Excludes the deps root from the project if the deps root
+// is a sub-directory of the project's root directory
+func ignoreDepsRoot() error {
+	if len(depsRoot) == 0 {
+		return nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	if relPath, isWithin := relativePath(cwd, depsRoot); isWithin && (len(relPath) > 0) {
+		if *flag_debug {
+			println("ignore dir (deps root) \"" + relPath + "\"")
+		}
+		project.ignoredDirs[relPath] = 0
+	}
+
+	return nil
 }
 
 var goCompilerVersion *uint = nil
@@ -118,3 +207,27 @@ func getGoCompilerVersion() (uint, error) {
 
 	return *goCompilerVersion, nil
 }
+
+// Checks that the tools needed for building the project can be found.
+// This is done before building anything, so that a missing tool
+// is reported up front instead of in the middle of a build.
+func checkBuildTools(root *dir_t, install bool) error {
+	exes := []*Executable{goCompiler_exe, goArchiver_exe, goLinker_exe}
+	if root.containsMakefiles() {
+		exes = append(exes, make_exe)
+	}
+	if len(project.yaccGrammars) > 0 {
+		exes = append(exes, yacc_exe)
+	}
+	for _, p := range project.protoFiles {
+		if p.goFile != nil {
+			exes = append(exes, protoc_exe)
+			break
+		}
+	}
+	if install {
+		exes = append(exes, cp_exe)
+	}
+
+	return lookupExecutables(exes)
+}
@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"go/token"
 	"io/ioutil"
+	"os"
 	pathutil "path"
+	"sort"
 	"strings"
 	"sync"
+	"unicode"
 )
 
 const (
@@ -18,9 +21,36 @@ const (
 	testExeName    = "package-test"
 )
 
-var currentConfig *config_file_t = nil
 var configCurrent_mutex sync.Mutex
 
+// Settings declared by a config file.
+// The settings are inherited by config files in sub-directories,
+// which can override them.
+type config_settings_t struct {
+	compilerFlags   []string // Additional flags passed to the Go compiler
+	linkerFlags     []string // Additional flags passed to the Go linker
+	buildTags       []string // Tags satisfying "+build" constraints in Go files
+	ignoredDirNames []string // Patterns matched against names of sub-directories
+	importPrefix    string   // The import path of the directory 'importPrefixDir' (can be empty)
+	importPrefixDir string   // The directory which declared the import prefix, empty if there is no prefix
+
+	minCompilerVersion uint // The minimum version of the Go compiler, 0 if there is no constraint
+
+	// Optional file types enabled (true) or disabled (false) by config files.
+	// The map is shared by clones, it is copied before being modified.
+	fileTypes map[string]bool
+}
+
+func (s *config_settings_t) clone() *config_settings_t {
+	c := *s
+	return &c
+}
+
+// Returns whether import paths can be derived from directory paths
+func (s *config_settings_t) haveImportPrefix() bool {
+	return len(s.importPrefixDir) != 0
+}
+
 // Reads the specified config file
 func readConfig(config *config_file_t) error {
 	var err error
@@ -31,19 +61,40 @@ func readConfig(config *config_file_t) error {
 			println("read config:", config.path)
 		}
 
-		currentConfig = config
+		project.currentConfig = config
+
+		// Inherit the settings from parent directories
+		if config.parent.parent_orNil != nil {
+			config.settings = config.parent.parent_orNil.settings().clone()
+		} else {
+			config.settings = project.defaultSettings.clone()
+		}
 
 		w := eval.NewWorld()
 		defineConstants(w)
 		defineFunctions(w)
+
+		project.currentWorld = w
+		project.includeStack = []string{config.path}
+
 		err = loadAndRunScript(w, config.path)
 
-		currentConfig = nil
+		project.includeStack = nil
+		project.currentWorld = nil
+		project.currentConfig = nil
 	}
 	configCurrent_mutex.Unlock()
 
 	if err != nil {
-		err = errors.New(config.Path() + ": " + err.Error())
+		// Errors reported by 'configError' already start with the path of the config file
+		if !strings.HasPrefix(err.Error(), config.Path()) {
+			err = errors.New(config.Path() + ": " + err.Error())
+		}
+
+		if project.checkingConfig {
+			project.configDiagnostics = append(project.configDiagnostics, err.Error())
+			err = nil
+		}
 	}
 
 	return err
@@ -58,7 +109,11 @@ func loadAndRunScript(w *eval.World, path string) error {
 
 	var buf bytes.Buffer
 	buf.Write(data)
-	return runScript(w, path, buf.String())
+	sourceCode := buf.String()
+
+	project.scriptCalls[path] = findCallSites(path, sourceCode)
+
+	return runScript(w, path, sourceCode)
 }
 
 // Runs the specified Go source code in the context of 'w'
@@ -115,98 +170,279 @@ func defineConstants(w *eval.World) {
 func defineFunctions(w *eval.World) {
 	{
 		var functionSignature func(string)
-		funcType, funcValue := eval.FuncFromNativeTyped(wrapper_Package, functionSignature)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("Package", wrapper_Package), functionSignature)
 		w.DefineVar("Package", funcType, funcValue)
 	}
 
+	{
+		var functionSignature func()
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("AutoPackage", wrapper_AutoPackage), functionSignature)
+		w.DefineVar("AutoPackage", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("ImportPrefix", wrapper_ImportPrefix), functionSignature)
+		w.DefineVar("ImportPrefix", funcType, funcValue)
+	}
+
 	{
 		var functionSignature func(string)
-		funcType, funcValue := eval.FuncFromNativeTyped(wrapper_PackageFiles, functionSignature)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("PackageFiles", wrapper_PackageFiles), functionSignature)
 		w.DefineVar("PackageFiles", funcType, funcValue)
 	}
 
 	{
 		var functionSignature func(string, string)
-		funcType, funcValue := eval.FuncFromNativeTyped(wrapper_Executable, functionSignature)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("Executable", wrapper_Executable), functionSignature)
 		w.DefineVar("Executable", funcType, funcValue)
 	}
 
 	{
 		var functionSignature func(string)
-		funcType, funcValue := eval.FuncFromNativeTyped(wrapper_IgnoreDir, functionSignature)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("IgnoreDir", wrapper_IgnoreDir), functionSignature)
 		w.DefineVar("IgnoreDir", funcType, funcValue)
 	}
 
 	{
 		var functionSignature func(string)
-		funcType, funcValue := eval.FuncFromNativeTyped(wrapper_DisableGoFmt, functionSignature)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("DisableGoFmt", wrapper_DisableGoFmt), functionSignature)
 		w.DefineVar("DisableGoFmt", funcType, funcValue)
 	}
 
 	{
 		var functionSignature func(uint)
-		funcType, funcValue := eval.FuncFromNativeTyped(wrapper_MinGoamVersion, functionSignature)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("MinGoamVersion", wrapper_MinGoamVersion), functionSignature)
 		w.DefineVar("MinGoamVersion", funcType, funcValue)
 	}
 
 	{
 		var functionSignature func(uint)
-		funcType, funcValue := eval.FuncFromNativeTyped(wrapper_MinCompilerVersion, functionSignature)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("MinCompilerVersion", wrapper_MinCompilerVersion), functionSignature)
 		w.DefineVar("MinCompilerVersion", funcType, funcValue)
 	}
 
 	{
 		var functionSignature func()
-		funcType, funcValue := eval.FuncFromNativeTyped(wrapper_InstallPackage, functionSignature)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("InstallPackage", wrapper_InstallPackage), functionSignature)
 		w.DefineVar("InstallPackage", funcType, funcValue)
 	}
 
 	{
 		var functionSignature func(string)
-		funcType, funcValue := eval.FuncFromNativeTyped(wrapper_InstallExecutable, functionSignature)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("InstallExecutable", wrapper_InstallExecutable), functionSignature)
 		w.DefineVar("InstallExecutable", funcType, funcValue)
 	}
 
 	{
 		var functionSignature func(string, string)
-		funcType, funcValue := eval.FuncFromNativeTyped(wrapper_InstallDir, functionSignature)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("InstallDir", wrapper_InstallDir), functionSignature)
 		w.DefineVar("InstallDir", funcType, funcValue)
 	}
 
 	{
 		var functionSignature func(string, string, string, []string)
-		funcType, funcValue := eval.FuncFromNativeTyped(wrapper_RemotePackage, functionSignature)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("RemotePackage", wrapper_RemotePackage), functionSignature)
 		w.DefineVar("RemotePackage", funcType, funcValue)
 	}
+
+	{
+		var functionSignature func(string, string, string, string, []string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("RemotePackageRev", wrapper_RemotePackageRev), functionSignature)
+		w.DefineVar("RemotePackageRev", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("DepsRoot", wrapper_DepsRoot), functionSignature)
+		w.DefineVar("DepsRoot", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string, string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("Replace", wrapper_Replace), functionSignature)
+		w.DefineVar("Replace", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string, string, []string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("Command", wrapper_Command), functionSignature)
+		w.DefineVar("Command", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string, string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("Yacc", wrapper_Yacc), functionSignature)
+		w.DefineVar("Yacc", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string, string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("EmbedFiles", wrapper_EmbedFiles), functionSignature)
+		w.DefineVar("EmbedFiles", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func([]string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("BeforeBuild", wrapper_BeforeBuild), functionSignature)
+		w.DefineVar("BeforeBuild", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func([]string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("AfterBuild", wrapper_AfterBuild), functionSignature)
+		w.DefineVar("AfterBuild", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func([]string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("AfterTest", wrapper_AfterTest), functionSignature)
+		w.DefineVar("AfterTest", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("Protos", wrapper_Protos), functionSignature)
+		w.DefineVar("Protos", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("EnableFileTypes", wrapper_EnableFileTypes), functionSignature)
+		w.DefineVar("EnableFileTypes", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("DisableFileTypes", wrapper_DisableFileTypes), functionSignature)
+		w.DefineVar("DisableFileTypes", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("CompilerFlags", wrapper_CompilerFlags), functionSignature)
+		w.DefineVar("CompilerFlags", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("LinkerFlags", wrapper_LinkerFlags), functionSignature)
+		w.DefineVar("LinkerFlags", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("BuildTags", wrapper_BuildTags), functionSignature)
+		w.DefineVar("BuildTags", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("IgnoreDirNames", wrapper_IgnoreDirNames), functionSignature)
+		w.DefineVar("IgnoreDirNames", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string)
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("Include", wrapper_Include), functionSignature)
+		w.DefineVar("Include", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string) string
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("Getenv", wrapper_Getenv), functionSignature)
+		w.DefineVar("Getenv", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func(string, string) string
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("Option", wrapper_Option), functionSignature)
+		w.DefineVar("Option", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func() string
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("ParentPackage", wrapper_ParentPackage), functionSignature)
+		w.DefineVar("ParentPackage", funcType, funcValue)
+	}
+
+	{
+		var functionSignature func() string
+		funcType, funcValue := eval.FuncFromNativeTyped(traced("ProjectRoot", wrapper_ProjectRoot), functionSignature)
+		w.DefineVar("ProjectRoot", funcType, funcValue)
+	}
 }
 
 // Signature: func Package(path string)
 func wrapper_Package(t *eval.Thread, in []eval.Value, out []eval.Value) {
 	pkg := in[0].(eval.StringValue).Get(t)
 
-	if len(currentConfig.targetPackage_orEmpty) != 0 {
-		t.Abort(errors.New("duplicate target package specification"))
+	err := setTargetPackage(pkg)
+	if err != nil {
+		configError(t, err)
+		return
+	}
+}
+
+// Signature: func AutoPackage()
+func wrapper_AutoPackage(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	pkg, err := project.currentConfig.parent.autoImportPath()
+	if err != nil {
+		configError(t, err)
+		return
+	}
+
+	err = setTargetPackage(pkg)
+	if err != nil {
+		configError(t, err)
 		return
 	}
+}
+
+func setTargetPackage(pkg string) error {
+	if len(project.currentConfig.targetPackage_orEmpty) != 0 {
+		return errors.New("duplicate target package specification")
+	}
 
 	pkg = strings.TrimSpace(pkg)
 	if len(pkg) == 0 {
-		t.Abort(errors.New("the target package cannot be an empty string"))
-		return
+		return errors.New("the target package cannot be an empty string")
 	}
 
 	if *flag_debug {
 		println("(read config) target package = \"" + pkg + "\"")
 	}
-	currentConfig.targetPackage_orEmpty = pkg
+	project.currentConfig.targetPackage_orEmpty = pkg
+
+	return nil
+}
+
+// Signature: func ImportPrefix(prefix string)
+func wrapper_ImportPrefix(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	prefix := strings.TrimSpace(in[0].(eval.StringValue).Get(t))
+
+	if len(prefix) == 0 {
+		configError(t, errors.New("the import prefix cannot be an empty string"))
+		return
+	}
+	if strings.HasPrefix(prefix, "/") || strings.HasSuffix(prefix, "/") || (pathutil.Clean(prefix) != prefix) {
+		configError(t, errors.New("invalid import prefix \"" + prefix + "\""))
+		return
+	}
+
+	if *flag_debug {
+		println("(read config) import prefix = \"" + prefix + "\"")
+	}
+	project.currentConfig.settings.importPrefix = prefix
+	project.currentConfig.settings.importPrefixDir = project.currentConfig.parent.path
 }
 
 // Signature: func PackageFiles(files string)
 func wrapper_PackageFiles(t *eval.Thread, in []eval.Value, out []eval.Value) {
 	_files := in[0].(eval.StringValue).Get(t)
 
-	if currentConfig.packageFiles_orNil != nil {
-		t.Abort(errors.New("package files already defined"))
+	if project.currentConfig.packageFiles_orNil != nil {
+		configError(t, errors.New("package files already defined"))
 		return
 	}
 
@@ -214,33 +450,46 @@ func wrapper_PackageFiles(t *eval.Thread, in []eval.Value, out []eval.Value) {
 	{
 		files = strings.Fields(_files)
 		if len(files) == 0 {
-			t.Abort(errors.New("empty list of files"))
+			configError(t, errors.New("empty list of files"))
 			return
 		}
 
+		// Expand patterns
+		if containsPatterns(files) {
+			var err error
+			files, err = project.currentConfig.parent.expandFilePatterns(files)
+			if err != nil {
+				configError(t, err)
+				return
+			}
+		}
+
 		// Check 'files[i]'
 		for i := 0; i < len(files); i++ {
 			file, err := cleanAndCheckPath(t, files[i])
 			if err != nil {
-				t.Abort(err)
+				configError(t, err)
 				return
 			}
 
 			if !strings.HasSuffix(file, ".go") {
-				t.Abort(errors.New("the name of file \"" + file + "\" does not end with \".go\""))
+				configError(t, errors.New("the name of file \"" + file + "\" does not end with \".go\""))
 				return
 			}
 
 			dir, _ := pathutil.Split(file)
 			if len(dir) > 0 {
-				t.Abort(errors.New("package file \"" + file + "\" uses a relative path"))
+				configError(t, errors.New("package file \"" + file + "\" uses a relative path"))
 				return
 			}
 
-			path := pathutil.Join(currentConfig.parent.path, file)
+			path := pathutil.Join(project.currentConfig.parent.path, file)
 
-			if !fileExists(path) {
-				t.Abort(errors.New("file \"" + path + "\" does not exist"))
+			// The outputs of commands defined earlier do not need to exist yet
+			_, generated := project.commands_byOutput[path]
+
+			if !generated && !fileExists(path) {
+				configError(t, errors.New("file \"" + path + "\" does not exist"))
 				return
 			}
 
@@ -256,12 +505,17 @@ func wrapper_PackageFiles(t *eval.Thread, in []eval.Value, out []eval.Value) {
 	for _, file := range files {
 		packageFiles[file] = 0
 	}
-	currentConfig.packageFiles_orNil = packageFiles
-}
+	project.currentConfig.packageFiles_orNil = packageFiles
 
-// Mapping between [the path of an executable] and [the paths of Go files from which to build the executable]
-var executable2sources = make(map[string][]string)
-var source2executable = make(map[string]string)
+	// The package can be specified after the package files
+	config := project.currentConfig
+	addConfigCheck(func(root *dir_t) error {
+		if (len(config.targetPackage_orEmpty) == 0) && !config.settings.haveImportPrefix() {
+			return errors.New("package files are specified, but the package is not specified")
+		}
+		return nil
+	})
+}
 
 // Signature: func Executable(name string, sources string)
 func wrapper_Executable(t *eval.Thread, in []eval.Value, out []eval.Value) {
@@ -273,74 +527,124 @@ func wrapper_Executable(t *eval.Thread, in []eval.Value, out []eval.Value) {
 		var err error
 		name, err = cleanAndCheckPath(t, name)
 		if err != nil {
-			t.Abort(err)
+			configError(t, err)
 			return
 		}
 
 		_, file := pathutil.Split(name)
 		if file == testExeName {
-			t.Abort(errors.New("executables named \"" + name + "\" are used for tests"))
+			configError(t, errors.New("executables named \"" + name + "\" are used for tests"))
 			return
 		}
 
-		name = pathutil.Join(currentConfig.parent.path, name)
-		if _, alreadyPresent := executable2sources[name]; alreadyPresent {
-			t.Abort(errors.New("duplicate executable \"" + name + "\""))
+		name = pathutil.Join(project.currentConfig.parent.path, name)
+		if _, alreadyPresent := project.executable2sources[name]; alreadyPresent {
+			configError(t, errors.New("duplicate executable \"" + name + "\""))
 			return
 		}
 	}
 
-	var sources []string
-	{
-		sources = strings.Fields(_sources)
-		if len(sources) == 0 {
-			t.Abort(errors.New("empty list of sources"))
-			return
+	sources := strings.Fields(_sources)
+	if len(sources) == 0 {
+		configError(t, errors.New("empty list of sources"))
+		return
+	}
+
+	if containsPatterns(sources) {
+		// The patterns can refer to files in sub-directories
+		// which have not been read yet. Expand them after all directories are read.
+		if *flag_debug {
+			fmt.Printf("(read config) exe \"%s\" <-- patterns %v\n", name, sources)
 		}
 
-		// Check 'sources[i]', make 'sources[i]' relative to the local root
-		for i := 0; i < len(sources); i++ {
-			source, err := cleanAndCheckPath(t, sources[i])
+		project.executable2sources[name] = nil
+		project.pendingExecutables = append(project.pendingExecutables, &pending_executable_t{project.currentConfig, project.currentCallPosition, name, sources})
+		return
+	}
+
+	err := addExecutableSources(project.currentConfig, name, sources)
+	if err != nil {
+		configError(t, err)
+		return
+	}
+}
+
+// An executable whose sources are specified by patterns
+type pending_executable_t struct {
+	config   *config_file_t
+	pos      string // The position of the call to 'Executable'
+	name     string
+	patterns []string
+}
+
+// Expands the patterns of all executables defined with patterns
+func expandPendingExecutables() error {
+	for _, pending := range project.pendingExecutables {
+		sources, err := pending.config.parent.expandFilePatterns(pending.patterns)
+		if err != nil {
+			err = deferredConfigError(pending.pos, errors.New("executable \""+pending.name+"\": "+err.Error()))
 			if err != nil {
-				t.Abort(err)
-				return
+				return err
 			}
+			continue
+		}
 
-			if !strings.HasSuffix(source, ".go") {
-				t.Abort(errors.New("the name of file \"" + source + "\" does not end with \".go\""))
-				return
+		err = addExecutableSources(pending.config, pending.name, sources)
+		if err != nil {
+			err = deferredConfigError(pending.pos, err)
+			if err != nil {
+				return err
 			}
+		}
+	}
+
+	project.pendingExecutables = nil
+	return nil
+}
 
-			source = pathutil.Join(currentConfig.parent.path, source)
+// Checks 'sources' and associates them with the executable.
+// The 'sources' are relative to the directory of the 'config' file.
+func addExecutableSources(config *config_file_t, name string, sources []string) error {
+	// Check 'sources[i]', make 'sources[i]' relative to the local root
+	for i := 0; i < len(sources); i++ {
+		source, err := cleanAndCheckPath(nil, sources[i])
+		if err != nil {
+			return err
+		}
 
-			if _, alreadyPresent := source2executable[source]; alreadyPresent {
-				t.Abort(errors.New("cannot associate file \"" + source + "\" with more than one executable"))
-				return
-			}
+		if !strings.HasSuffix(source, ".go") {
+			return errors.New("the name of file \"" + source + "\" does not end with \".go\"")
+		}
 
-			if !fileExists(source) {
-				t.Abort(errors.New("executable \"" + name + "\" depends on non-existent file \"" + source + "\""))
-				return
-			}
+		source = pathutil.Join(config.parent.path, source)
 
-			sources[i] = source
+		if _, alreadyPresent := project.source2executable[source]; alreadyPresent {
+			return errors.New("cannot associate file \"" + source + "\" with more than one executable")
 		}
+
+		if !fileExists(source) {
+			return errors.New("executable \"" + name + "\" depends on non-existent file \"" + source + "\"")
+		}
+
+		sources[i] = source
 	}
 
 	if *flag_debug {
 		fmt.Printf("(read config) exe \"%s\" <-- %v\n", name, sources)
 	}
 
-	executable2sources[name] = sources
+	project.executable2sources[name] = sources
 	for _, source := range sources {
-		source2executable[source] = name
+		project.source2executable[source] = name
 	}
+
+	return nil
 }
 
 // Checks the existence and contents of Go source code files
 // associated with all executables in 'executable2sources'
 func check_executable2sources(root *dir_t) error {
-	for executable, sources := range executable2sources {
+	for executable, sources := range project.executable2sources {
 		for _, source := range sources {
 			var object object_t = root.getObject_orNil(strings.Split(source, "/"))
 
@@ -348,6 +652,10 @@ func check_executable2sources(root *dir_t) error {
 				return errors.New("executable \"" + executable + "\" depends on non-existent object \"" + source + "\"")
 			}
 
+			if goFile, ok := object.(*go_file_t); ok && goFile.notGeneratedYet() {
+				continue
+			}
+
 			if src, ok := object.(go_source_code_t); ok {
 				contents, err := src.Contents()
 				if err != nil {
@@ -384,32 +692,95 @@ func cleanAndCheckPath(t *eval.Thread, path string) (string, error) {
 	return path, nil
 }
 
-// Set of directories to ignore.
-// This is a set, the values of this hash-map have no meaning.
-var ignoredDirs = make(map[string]byte)
+// A pattern passed to 'IgnoreDir'
+type ignored_dir_pattern_t struct {
+	config    *config_file_t
+	pos       string // The position of the call to 'IgnoreDir'
+	pattern   string // Relative to the directory of 'config'
+	exception bool   // Whether the pattern started with "!"
+	matched   bool
+}
+
+// Returns whether the directory should be ignored because of a pattern passed to 'IgnoreDir'
+func isIgnoredByPattern(dir *dir_t) bool {
+	ignore := false
+	for _, p := range project.ignoredDirPatterns {
+		relPath, isWithin := relativePath(p.config.parent.path, dir.path)
+		if !isWithin || (len(relPath) == 0) {
+			continue
+		}
+
+		// Errors have been reported by 'wrapper_IgnoreDir'
+		if matched, _ := matchGlob(p.pattern, relPath); matched {
+			p.matched = true
+			if p.exception {
+				return false
+			}
+			ignore = true
+		}
+	}
+
+	return ignore
+}
+
+// Reports patterns passed to 'IgnoreDir' which did not match any directory
+func checkIgnoredDirPatterns() error {
+	for _, p := range project.ignoredDirPatterns {
+		if !p.matched && !p.exception {
+			err := deferredConfigError(p.pos, errors.New("pattern \""+p.pattern+"\" passed to IgnoreDir does not match any directory"))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
 
 // Signature: func IgnoreDir(path string)
 func wrapper_IgnoreDir(t *eval.Thread, in []eval.Value, out []eval.Value) {
 	path := in[0].(eval.StringValue).Get(t)
 
+	exception := strings.HasPrefix(path, "!")
+	if exception {
+		path = path[1:]
+	}
+
 	var err error
 	path, err = cleanAndCheckPath(t, path)
 	if err != nil {
-		t.Abort(err)
+		configError(t, err)
+		return
+	}
+
+	if exception || isGlobPattern(path) {
+		_, err = matchGlob(path, "")
+		if err != nil {
+			configError(t, err)
+			return
+		}
+
+		if *flag_debug {
+			println("(read config) ignore dir pattern \"" + path + "\"")
+		}
+		project.ignoredDirPatterns = append(project.ignoredDirPatterns, &ignored_dir_pattern_t{project.currentConfig, project.currentCallPosition, path, exception, false})
 		return
 	}
 
-	path = pathutil.Join(currentConfig.parent.path, path)
+	path = pathutil.Join(project.currentConfig.parent.path, path)
 
 	if *flag_debug {
 		println("(read config) ignore dir \"" + path + "\"")
 	}
-	ignoredDirs[path] = 0
-}
+	project.ignoredDirs[path] = 0
 
-// Set of files for which gofmt is disabled.
-// This is a set, the values of this hash-map have no meaning.
-var disabledGoFmt = make(map[string]byte)
+	addConfigCheck(func(root *dir_t) error {
+		if !fileExists(path) {
+			return errors.New("ignored directory \"" + path + "\" does not exist")
+		}
+		return nil
+	})
+}
 
 // Signature: func DisableGoFmt(path string)
 func wrapper_DisableGoFmt(t *eval.Thread, in []eval.Value, out []eval.Value) {
@@ -418,21 +789,21 @@ func wrapper_DisableGoFmt(t *eval.Thread, in []eval.Value, out []eval.Value) {
 	var err error
 	path, err = cleanAndCheckPath(t, path)
 	if err != nil {
-		t.Abort(err)
+		configError(t, err)
 		return
 	}
 
-	path = pathutil.Join(currentConfig.parent.path, path)
+	path = pathutil.Join(project.currentConfig.parent.path, path)
 
-	if _, alreadyPresent := disabledGoFmt[path]; alreadyPresent {
-		t.Abort(errors.New("gofmt already disabled: \"" + path + "\""))
+	if _, alreadyPresent := project.disabledGoFmt[path]; alreadyPresent {
+		configError(t, errors.New("gofmt already disabled: \"" + path + "\""))
 		return
 	}
 
 	if *flag_debug {
 		println("(read config) disable gofmt \"" + path + "\"")
 	}
-	disabledGoFmt[path] = 0
+	project.disabledGoFmt[path] = 0
 }
 
 const VERSION = 2
@@ -447,7 +818,7 @@ func wrapper_MinGoamVersion(t *eval.Thread, in []eval.Value, out []eval.Value) {
 
 	if VERSION < minVersion {
 		msg := fmt.Sprintf("insufficient GOAM version: %d, minimum required version is %d", VERSION, minVersion)
-		t.Abort(errors.New(msg))
+		configError(t, errors.New(msg))
 		return
 	}
 }
@@ -461,33 +832,50 @@ func wrapper_MinCompilerVersion(t *eval.Thread, in []eval.Value, out []eval.Valu
 	}
 
 	if *flag_gcc {
-		t.Abort(errors.New("function MinCompilerVersion is incompatible with gccgo"))
+		configError(t, errors.New("function MinCompilerVersion is incompatible with gccgo"))
 		return
 	}
 
-	version, err := getGoCompilerVersion()
+	// The constraint is inherited by the sub-directories.
+	// It is enforced right away, regardless of whether anything is compiled.
+	project.currentConfig.settings.minCompilerVersion = uint(minVersion)
+
+	err := project.currentConfig.settings.checkMinCompilerVersion()
 	if err != nil {
-		t.Abort(err)
+		configError(t, err)
 		return
 	}
+}
 
-	if uint64(version) < minVersion {
-		msg := fmt.Sprintf("insufficient Go compiler version: %d, minimum required version is %d", version, minVersion)
-		t.Abort(errors.New(msg))
-		return
+// Checks that the version of the Go compiler satisfies the constraint declared by the settings
+func (s *config_settings_t) checkMinCompilerVersion() error {
+	if (s.minCompilerVersion == 0) || *flag_gcc {
+		return nil
+	}
+
+	version, err := getGoCompilerVersion()
+	if err != nil {
+		return err
+	}
+
+	if version < s.minCompilerVersion {
+		msg := fmt.Sprintf("insufficient Go compiler version: %d, minimum required version is %d", version, s.minCompilerVersion)
+		return errors.New(msg)
 	}
+
+	return nil
 }
 
 // Signature: func InstallPackage()
 func wrapper_InstallPackage(t *eval.Thread, in []eval.Value, out []eval.Value) {
-	pkg := currentConfig.targetPackage_orEmpty
+	pkg := project.currentConfig.targetPackage_orEmpty
 	if len(pkg) == 0 {
-		t.Abort(errors.New("no target package has been defined"))
+		configError(t, errors.New("no target package has been defined"))
 		return
 	}
 
-	if _, alreadyPresent := installationCommands_packagesByImport[pkg]; alreadyPresent {
-		t.Abort(errors.New("duplicate installation of package \"" + pkg + "\""))
+	if _, alreadyPresent := project.installationCommands_packagesByImport[pkg]; alreadyPresent {
+		configError(t, errors.New("duplicate installation of package \"" + pkg + "\""))
 		return
 	}
 
@@ -496,8 +884,8 @@ func wrapper_InstallPackage(t *eval.Thread, in []eval.Value, out []eval.Value) {
 	}
 
 	cmd := new_installPackage(pkg)
-	installationCommands = append(installationCommands, cmd)
-	installationCommands_packagesByImport[pkg] = cmd
+	project.installationCommands = append(project.installationCommands, cmd)
+	project.installationCommands_packagesByImport[pkg] = cmd
 }
 
 // Signature: func InstallExecutable(srcPath string)
@@ -510,19 +898,19 @@ func wrapper_InstallExecutable(t *eval.Thread, in []eval.Value, out []eval.Value
 	{
 		srcPath, err = cleanAndCheckPath(t, srcPath)
 		if err != nil {
-			t.Abort(err)
+			configError(t, err)
 			return
 		}
 
 		_, file := pathutil.Split(srcPath)
 		if file == testExeName {
-			t.Abort(errors.New("cannot install: executables named \"" + srcPath + "\" are used for tests"))
+			configError(t, errors.New("cannot install: executables named \"" + srcPath + "\" are used for tests"))
 			return
 		}
 
-		srcPath = pathutil.Join(currentConfig.parent.path, srcPath)
-		if _, alreadyPresent := installationCommands_bySrcPath[srcPath]; alreadyPresent {
-			t.Abort(errors.New("duplicate installation of \"" + srcPath + "\""))
+		srcPath = pathutil.Join(project.currentConfig.parent.path, srcPath)
+		if _, alreadyPresent := project.installationCommands_bySrcPath[srcPath]; alreadyPresent {
+			configError(t, errors.New("duplicate installation of \"" + srcPath + "\""))
 			return
 		}
 	}
@@ -532,8 +920,12 @@ func wrapper_InstallExecutable(t *eval.Thread, in []eval.Value, out []eval.Value
 	}
 
 	cmd := new_installExecutable(srcPath)
-	installationCommands = append(installationCommands, cmd)
-	installationCommands_bySrcPath[srcPath] = cmd
+	project.installationCommands = append(project.installationCommands, cmd)
+	project.installationCommands_bySrcPath[srcPath] = cmd
+
+	addConfigCheck(func(root *dir_t) error {
+		return checkInstalledExecutable(root, srcPath)
+	})
 }
 
 // Signature: func InstallDir(srcPath, dstPath string)
@@ -547,13 +939,13 @@ func wrapper_InstallDir(t *eval.Thread, in []eval.Value, out []eval.Value) {
 	{
 		srcPath, err = cleanAndCheckPath(t, srcPath)
 		if err != nil {
-			t.Abort(err)
+			configError(t, err)
 			return
 		}
 
-		srcPath = pathutil.Join(currentConfig.parent.path, srcPath)
-		if _, alreadyPresent := installationCommands_bySrcPath[srcPath]; alreadyPresent {
-			t.Abort(errors.New("duplicate installation of \"" + srcPath + "\""))
+		srcPath = pathutil.Join(project.currentConfig.parent.path, srcPath)
+		if _, alreadyPresent := project.installationCommands_bySrcPath[srcPath]; alreadyPresent {
+			configError(t, errors.New("duplicate installation of \"" + srcPath + "\""))
 			return
 		}
 	}
@@ -566,8 +958,15 @@ func wrapper_InstallDir(t *eval.Thread, in []eval.Value, out []eval.Value) {
 	}
 
 	cmd := new_installDir(srcPath, dstPath)
-	installationCommands = append(installationCommands, cmd)
-	installationCommands_bySrcPath[srcPath] = cmd
+	project.installationCommands = append(project.installationCommands, cmd)
+	project.installationCommands_bySrcPath[srcPath] = cmd
+
+	addConfigCheck(func(root *dir_t) error {
+		if !fileExists(srcPath) {
+			return errors.New("cannot install \"" + srcPath + "\": no such directory")
+		}
+		return nil
+	})
 }
 
 // Signature: func RemotePackage(importPaths, type, repository string, installCommand []string)
@@ -577,6 +976,32 @@ func wrapper_RemotePackage(t *eval.Thread, in []eval.Value, out []eval.Value) {
 	repositoryPath := in[2].(eval.StringValue).Get(t)
 	installCommand_sliceValue := in[3].(eval.SliceValue).Get(t)
 
+	remotePackage(t, importPaths, kindString, repositoryPath, /*revision*/ "", installCommand_sliceValue)
+}
+
+// Signature: func RemotePackageRev(importPaths, type, repository, revision string, installCommand []string)
+func wrapper_RemotePackageRev(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	importPaths := in[0].(eval.StringValue).Get(t)
+	kindString := in[1].(eval.StringValue).Get(t)
+	repositoryPath := in[2].(eval.StringValue).Get(t)
+	revision := strings.TrimSpace(in[3].(eval.StringValue).Get(t))
+	installCommand_sliceValue := in[4].(eval.SliceValue).Get(t)
+
+	if len(revision) == 0 {
+		configError(t, errors.New("repository \"" + repositoryPath + "\": empty revision"))
+		return
+	}
+	// A revision starting with "sha256:" would be taken for a checksum in the lock file
+	if strings.HasPrefix(revision, "-") || strings.HasPrefix(revision, checksumPrefix) || strings.ContainsAny(revision, " \t") {
+		configError(t, errors.New("repository \"" + repositoryPath + "\": invalid revision \"" + revision + "\""))
+		return
+	}
+
+	remotePackage(t, importPaths, kindString, repositoryPath, revision, installCommand_sliceValue)
+}
+
+func remotePackage(t *eval.Thread, importPaths, kindString, repositoryPath, revision string, installCommand_sliceValue eval.Slice) {
+
 	var importPaths_array []string
 	{
 		importPaths_array = strings.Split(importPaths, " ")
@@ -598,7 +1023,7 @@ func wrapper_RemotePackage(t *eval.Thread, in []eval.Value, out []eval.Value) {
 		}
 
 		if len(importPaths_array) == 0 {
-			t.Abort(errors.New("repository \"" + repositoryPath + "\": empty list of import paths"))
+			configError(t, errors.New("repository \"" + repositoryPath + "\": empty list of import paths"))
 			return
 		}
 
@@ -607,7 +1032,7 @@ func wrapper_RemotePackage(t *eval.Thread, in []eval.Value, out []eval.Value) {
 			importPaths_set := make(map[string]byte)
 			for _, importPath := range importPaths_array {
 				if _, alreadyExists := importPaths_set[importPath]; alreadyExists {
-					t.Abort(errors.New("repository \"" + repositoryPath + "\": duplicate import path \"" + importPath + "\""))
+					configError(t, errors.New("repository \"" + repositoryPath + "\": duplicate import path \"" + importPath + "\""))
 					return
 				}
 
@@ -616,14 +1041,9 @@ func wrapper_RemotePackage(t *eval.Thread, in []eval.Value, out []eval.Value) {
 		}
 	}
 
-	var kind int
-	switch strings.ToLower(kindString) {
-	case "github":
-		kind = GITHUB
-	case "bitbucket":
-		kind = BITBUCKET
-	default:
-		t.Abort(errors.New("repository \"" + repositoryPath + "\": \"" + kindString + "\" is an invalid repository type"))
+	kind, err := parseRepositoryKind(kindString)
+	if err != nil {
+		configError(t, errors.New("repository \"" + repositoryPath + "\": " + err.Error()))
 		return
 	}
 
@@ -633,80 +1053,599 @@ func wrapper_RemotePackage(t *eval.Thread, in []eval.Value, out []eval.Value) {
 
 		err := checkRepositoryPath(kind, repositoryPath)
 		if err != nil {
-			t.Abort(err)
+			configError(t, err)
 			return
 		}
-	}
-
-	var installCommand []string
-	{
-		var array eval.ArrayValue = installCommand_sliceValue.Base
-		var length int64 = installCommand_sliceValue.Len
 
-		installCommand = make([]string, length)
-		for i := int64(0); i < length; i++ {
-			installCommand[i] = array.Elem(t, i).(eval.StringValue).Get(t)
+		if (kind == LOCAL) && (len(revision) != 0) {
+			configError(t, errors.New("local repository \"" + repositoryPath + "\": revisions are not supported"))
+			return
 		}
 
-		if len(installCommand) == 0 {
-			t.Abort(errors.New("repository \"" + repositoryPath + "\": empty installation command"))
-			return
+		// A relative path of a local repository is relative to the current directory
+		if (kind == LOCAL) && !pathutil.IsAbs(repositoryPath) {
+			repositoryPath = pathutil.Join(project.currentConfig.parent.path, repositoryPath)
 		}
 	}
 
+	// An empty installation command means that the package is built by GOAM itself
+	installCommand := stringSlice(t, installCommand_sliceValue)
+
 	if *flag_debug {
-		fmt.Printf("(read config) remote package: %v %s \"%s\"\n", importPaths_array, kindString, repositoryPath)
+		fmt.Printf("(read config) remote package: %v %s \"%s\" %s\n", importPaths_array, kindString, repositoryPath, revision)
 	}
 
-	// Find or create an instance of 'remote_package_t'
-	var remotePkg *remote_package_t
-	{
-		if remotePkg1, alreadyExists := remotePackages_byRepository[repositoryPath]; alreadyExists {
-			if remotePkg1.repository.Kind() != kind {
-				t.Abort(errors.New("repository \"" + repositoryPath + "\" redefined with a different repository type"))
-				return
-			}
+	_, err = defineRemotePackage(importPaths_array, kind, repositoryPath, revision, installCommand)
+	if err != nil {
+		configError(t, err)
+		return
+	}
+}
 
-			if len(remotePkg1.installCmd) != len(installCommand) {
-				t.Abort(errors.New("repository \"" + repositoryPath + "\" redefined with a different installation command"))
-				return
-			}
-			for i := 0; i < len(installCommand); i++ {
-				if remotePkg1.installCmd[i] != installCommand[i] {
-					t.Abort(errors.New("repository \"" + repositoryPath + "\" redefined with a different installation command"))
-					return
-				}
-			}
+// Signature: func DepsRoot(path string)
+func wrapper_DepsRoot(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	path := strings.TrimSpace(in[0].(eval.StringValue).Get(t))
 
-			remotePkg = remotePkg1
-		} else {
-			// Create a new object conforming to the interface 'repository_t'
-			var repository repository_t
-			switch kind {
-			case GITHUB:
-				repository = new_repository_github(repositoryPath)
-			case BITBUCKET:
-				repository = new_repository_bitbucket(repositoryPath)
-			default:
-				panic("invalid kind")
-			}
+	if project.currentConfig.parent.parent_orNil != nil {
+		configError(t, errors.New("DepsRoot can only be used in the config file of the root directory"))
+		return
+	}
+	if len(path) == 0 {
+		configError(t, errors.New("empty deps root"))
+		return
+	}
+	if (len(depsRoot) != 0) && !depsRootFixed {
+		configError(t, errors.New("the deps root has already been set to \"" + depsRoot + "\""))
+		return
+	}
 
-			// Create a new 'remote_package_t'
-			remotePkg = new_remotePackage(importPaths_array, repository, installCommand)
-			remotePackages = append(remotePackages, remotePkg)
-			remotePackages_byRepository[repositoryPath] = remotePkg
+	if depsRootFixed {
+		if *flag_debug {
+			println("(read config) ignore deps root \"" + path + "\"")
 		}
+		return
 	}
 
-	// Add to 'remotePackages_byImport'
-	for _, importPath := range importPaths_array {
-		if remotePkg1, exists := remotePackages_byImport[importPath]; exists {
-			if remotePkg1 != remotePkg {
-				t.Abort(errors.New("import path \"" + importPath + "\" maps to multiple distinct repositories"))
-				return
-			}
-		} else {
-			remotePackages_byImport[importPath] = remotePkg
+	if !pathutil.IsAbs(path) {
+		path = pathutil.Join(project.currentConfig.parent.path, path)
+	}
+
+	err := setDepsRoot(path)
+	if err == nil {
+		err = ignoreDepsRoot()
+	}
+	if err != nil {
+		configError(t, err)
+		return
+	}
+}
+
+// Signature: func Replace(importPath, localDir string)
+func wrapper_Replace(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	importPath := strings.TrimSpace(in[0].(eval.StringValue).Get(t))
+	dir := strings.TrimSpace(in[1].(eval.StringValue).Get(t))
+
+	if (len(importPath) == 0) || strings.ContainsAny(importPath, " \t") {
+		configError(t, errors.New("invalid import path \"" + importPath + "\""))
+		return
+	}
+	if len(dir) == 0 {
+		configError(t, errors.New("import path \"" + importPath + "\": empty local directory"))
+		return
+	}
+
+	if _, fixed := flag_replacements[importPath]; fixed {
+		if *flag_debug {
+			println("(read config) ignore replacement of \"" + importPath + "\", overridden by -replace")
+		}
+		return
+	}
+
+	// A relative path is relative to the current directory
+	if !pathutil.IsAbs(dir) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			configError(t, err)
+			return
+		}
+		dir = pathutil.Join(cwd, project.currentConfig.parent.path, dir)
+	}
+	dir = pathutil.Clean(dir)
+
+	if dir1, alreadyReplaced := project.replacements[importPath]; alreadyReplaced && (dir1 != dir) {
+		configError(t, errors.New("import path \"" + importPath + "\" is already replaced by \"" + dir1 + "\""))
+		return
+	}
+
+	if *flag_debug {
+		println("(read config) replace \"" + importPath + "\" by \"" + dir + "\"")
+	}
+	project.replacements[importPath] = dir
+
+	// Remote packages defined later are replaced by 'defineRemotePackage'
+	if remotePkg, exists := project.remotePackages_byImport[importPath]; exists {
+		err := remotePkg.replace(dir)
+		if err != nil {
+			configError(t, err)
+			return
+		}
+	}
+
+	addConfigCheck(func(root *dir_t) error {
+		if !fileExists(dir) {
+			return errors.New("local directory \"" + dir + "\" replacing \"" + importPath + "\" does not exist")
+		}
+		return nil
+	})
+}
+
+// Converts a slice of strings passed to a config function
+func stringSlice(t *eval.Thread, sliceValue eval.Slice) []string {
+	var array eval.ArrayValue = sliceValue.Base
+	var length int64 = sliceValue.Len
+
+	result := make([]string, length)
+	for i := int64(0); i < length; i++ {
+		result[i] = array.Elem(t, i).(eval.StringValue).Get(t)
+	}
+
+	return result
+}
+
+// Checks the paths, and makes them relative to the local root
+func configPaths(t *eval.Thread, paths []string) ([]string, error) {
+	result := make([]string, len(paths))
+	for i, path := range paths {
+		path, err := cleanAndCheckPath(t, path)
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = pathutil.Join(project.currentConfig.parent.path, path)
+	}
+
+	return result, nil
+}
+
+// Signature: func Command(outputs, inputs string, argv []string)
+func wrapper_Command(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	_outputs := in[0].(eval.StringValue).Get(t)
+	_inputs := in[1].(eval.StringValue).Get(t)
+	argv := stringSlice(t, in[2].(eval.SliceValue).Get(t))
+
+	if len(argv) == 0 {
+		configError(t, errors.New("empty command"))
+		return
+	}
+
+	outputs, err := configPaths(t, strings.Fields(_outputs))
+	if err != nil {
+		configError(t, err)
+		return
+	}
+	if len(outputs) == 0 {
+		configError(t, errors.New("command \"" + strings.Join(argv, " ") + "\": empty list of outputs"))
+		return
+	}
+
+	inputs, err := configPaths(t, strings.Fields(_inputs))
+	if err != nil {
+		configError(t, err)
+		return
+	}
+
+	if *flag_debug {
+		fmt.Printf("(read config) command %v <-- %v: %v\n", outputs, inputs, argv)
+	}
+
+	_, err = new_command(project.currentConfig.parent, outputs, inputs, argv)
+	if err != nil {
+		configError(t, err)
+		return
+	}
+}
+
+// Signature: func Yacc(grammar, options string)
+func wrapper_Yacc(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	grammar := in[0].(eval.StringValue).Get(t)
+	options := strings.Fields(in[1].(eval.StringValue).Get(t))
+
+	var err error
+	grammar, err = cleanAndCheckPath(t, grammar)
+	if err != nil {
+		configError(t, err)
+		return
+	}
+
+	if !strings.HasSuffix(grammar, ".y") {
+		configError(t, errors.New("the name of grammar \"" + grammar + "\" does not end with \".y\""))
+		return
+	}
+
+	grammar = pathutil.Join(project.currentConfig.parent.path, grammar)
+	if !fileExists(grammar) {
+		configError(t, errors.New("file \"" + grammar + "\" does not exist"))
+		return
+	}
+
+	if _, alreadyPresent := project.yaccOptions[grammar]; alreadyPresent {
+		configError(t, errors.New("duplicate options for grammar \"" + grammar + "\""))
+		return
+	}
+
+	err = checkYaccOptions(options)
+	if err != nil {
+		configError(t, err)
+		return
+	}
+
+	if *flag_debug {
+		fmt.Printf("(read config) yacc \"%s\" %v\n", grammar, options)
+	}
+
+	project.yaccOptions[grammar] = options
+}
+
+// Returns whether 's' is a valid Go identifier
+func isIdentifier(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for i, c := range s {
+		switch {
+		case c == '_', unicode.IsLetter(c):
+		case unicode.IsDigit(c) && (i > 0):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// Signature: func EmbedFiles(pkgVar, patterns string)
+func wrapper_EmbedFiles(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	variable := in[0].(eval.StringValue).Get(t)
+	patterns := strings.Fields(in[1].(eval.StringValue).Get(t))
+
+	if !isIdentifier(variable) {
+		configError(t, errors.New("invalid variable name \"" + variable + "\""))
+		return
+	}
+
+	for _, spec := range project.currentConfig.embeds {
+		if spec.variable == variable {
+			configError(t, errors.New("duplicate definition of embedded files \"" + variable + "\""))
+			return
+		}
+	}
+
+	if len(patterns) == 0 {
+		configError(t, errors.New("empty list of files to embed"))
+		return
+	}
+
+	for _, pattern := range patterns {
+		_, err := cleanAndCheckPath(t, strings.TrimPrefix(pattern, "!"))
+		if err != nil {
+			configError(t, err)
+			return
+		}
+	}
+
+	if *flag_debug {
+		fmt.Printf("(read config) embed %s <-- %v\n", variable, patterns)
+	}
+
+	// The patterns are expanded when the Go file is generated
+	project.currentConfig.embeds = append(project.currentConfig.embeds, &embed_spec_t{variable, patterns})
+}
+
+func addHook(t *eval.Thread, kind int, in []eval.Value) {
+	argv := stringSlice(t, in[0].(eval.SliceValue).Get(t))
+	if len(argv) == 0 {
+		configError(t, errors.New(hookNames[kind] + ": empty command"))
+		return
+	}
+
+	if *flag_debug {
+		fmt.Printf("(read config) %s %v\n", hookNames[kind], argv)
+	}
+
+	project.currentConfig.hooks[kind] = append(project.currentConfig.hooks[kind], argv)
+}
+
+// Signature: func BeforeBuild(argv []string)
+func wrapper_BeforeBuild(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	addHook(t, HOOK_BEFORE_BUILD, in)
+}
+
+// Signature: func AfterBuild(argv []string)
+func wrapper_AfterBuild(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	addHook(t, HOOK_AFTER_BUILD, in)
+}
+
+// Signature: func AfterTest(argv []string)
+func wrapper_AfterTest(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	addHook(t, HOOK_AFTER_TEST, in)
+}
+
+func setFileTypes(t *eval.Thread, in []eval.Value, enable bool) {
+	names, err := parseFileHandlerNames(in[0].(eval.StringValue).Get(t))
+	if err != nil {
+		configError(t, err)
+		return
+	}
+
+	setFileTypes_internal(names, enable)
+}
+
+func setFileTypes_internal(names []string, enable bool) {
+	fileTypes := make(map[string]bool)
+	for name, enabled := range project.currentConfig.settings.fileTypes {
+		fileTypes[name] = enabled
+	}
+	for _, name := range names {
+		fileTypes[name] = enable
+	}
+
+	if *flag_debug {
+		fmt.Printf("(read config) file types %v\n", fileTypes)
+	}
+
+	project.currentConfig.settings.fileTypes = fileTypes
+}
+
+// Signature: func Protos(patterns string)
+func wrapper_Protos(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	patterns := strings.Fields(in[0].(eval.StringValue).Get(t))
+
+	if project.currentConfig.protoPatterns != nil {
+		configError(t, errors.New("protocol buffer files already defined"))
+		return
+	}
+
+	if len(patterns) == 0 {
+		configError(t, errors.New("empty list of protocol buffer files"))
+		return
+	}
+
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") {
+			pattern = pattern[1:]
+		}
+
+		pattern, err := cleanAndCheckPath(t, pattern)
+		if err != nil {
+			configError(t, err)
+			return
+		}
+
+		if strings.Contains(pattern, "/") {
+			configError(t, errors.New("protocol buffer file \"" + pattern + "\" uses a relative path"))
+			return
+		}
+	}
+
+	if *flag_debug {
+		fmt.Printf("(read config) protos %v\n", patterns)
+	}
+
+	// The patterns are expanded after the protocol buffer files in the directory are identified
+	project.currentConfig.protoPatterns = patterns
+	setFileTypes_internal([]string{"proto"}, /*enable*/ true)
+}
+
+// Signature: func EnableFileTypes(names string)
+func wrapper_EnableFileTypes(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	setFileTypes(t, in, /*enable*/ true)
+}
+
+// Signature: func DisableFileTypes(names string)
+func wrapper_DisableFileTypes(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	setFileTypes(t, in, /*enable*/ false)
+}
+
+// Signature: func CompilerFlags(flags string)
+func wrapper_CompilerFlags(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	flags := strings.Fields(in[0].(eval.StringValue).Get(t))
+
+	if *flag_debug {
+		fmt.Printf("(read config) compiler flags: %v\n", flags)
+	}
+	project.currentConfig.settings.compilerFlags = flags
+}
+
+// Signature: func LinkerFlags(flags string)
+func wrapper_LinkerFlags(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	flags := strings.Fields(in[0].(eval.StringValue).Get(t))
+
+	if *flag_debug {
+		fmt.Printf("(read config) linker flags: %v\n", flags)
+	}
+	project.currentConfig.settings.linkerFlags = flags
+}
+
+// Signature: func BuildTags(tags string)
+func wrapper_BuildTags(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	tags := strings.Fields(in[0].(eval.StringValue).Get(t))
+
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, "!") || strings.Contains(tag, ",") {
+			configError(t, errors.New("invalid build tag \"" + tag + "\""))
+			return
+		}
+	}
+
+	if *flag_debug {
+		fmt.Printf("(read config) build tags: %v\n", tags)
+	}
+	project.currentConfig.settings.buildTags = tags
+}
+
+// Signature: func IgnoreDirNames(patterns string)
+func wrapper_IgnoreDirNames(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	patterns := strings.Fields(in[0].(eval.StringValue).Get(t))
+
+	for _, pattern := range patterns {
+		if strings.Contains(pattern, "/") {
+			configError(t, errors.New("directory name pattern \"" + pattern + "\" contains a slash"))
+			return
+		}
+
+		_, err := pathutil.Match(pattern, "")
+		if err != nil {
+			configError(t, errors.New("invalid directory name pattern \"" + pattern + "\""))
+			return
 		}
 	}
+
+	if *flag_debug {
+		fmt.Printf("(read config) ignore dir names: %v\n", patterns)
+	}
+	project.currentConfig.settings.ignoredDirNames = patterns
+}
+
+// Signature: func ParentPackage() string
+func wrapper_ParentPackage(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	var pkg string
+	for dir := project.currentConfig.parent.parent_orNil; dir != nil; dir = dir.parent_orNil {
+		if (dir.config_orNil != nil) && (len(dir.config_orNil.targetPackage_orEmpty) != 0) {
+			pkg = dir.config_orNil.targetPackage_orEmpty
+			break
+		}
+	}
+
+	out[0].(eval.StringValue).Set(t, pkg)
+}
+
+// Signature: func ProjectRoot() string
+func wrapper_ProjectRoot(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	root, err := os.Getwd()
+	if err != nil {
+		configError(t, err)
+		return
+	}
+
+	out[0].(eval.StringValue).Set(t, root)
+}
+
+// Signature: func Include(path string)
+func wrapper_Include(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	path := in[0].(eval.StringValue).Get(t)
+
+	var err error
+	path, err = cleanAndCheckPath(t, path)
+	if err != nil {
+		configError(t, err)
+		return
+	}
+
+	// The path is relative to the directory of the including file
+	includingFile := project.includeStack[len(project.includeStack)-1]
+	path = pathutil.Join(pathutil.Dir(includingFile), path)
+
+	for i, file := range project.includeStack {
+		if file == path {
+			var cycle []string
+			cycle = append(cycle, project.includeStack[i:]...)
+			cycle = append(cycle, path)
+			configError(t, errors.New("include cycle: " + strings.Join(cycle, " -> ")))
+			return
+		}
+	}
+
+	if *flag_debug {
+		println("(read config) include \"" + path + "\"")
+	}
+
+	project.includeStack = append(project.includeStack, path)
+	err = loadAndRunScript(project.currentWorld, path)
+	project.includeStack = project.includeStack[0 : len(project.includeStack)-1]
+
+	if err != nil {
+		msg := err.Error()
+		if !strings.HasPrefix(msg, path) {
+			msg = path + ": " + msg
+		}
+		configError(t, errors.New("Include(\"" + in[0].(eval.StringValue).Get(t) + "\"): " + msg))
+		return
+	}
+}
+
+// Signature: func Getenv(name string) string
+func wrapper_Getenv(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	name := in[0].(eval.StringValue).Get(t)
+
+	value := os.Getenv(name)
+
+	if *flag_debug {
+		println("(read config) getenv", name, "=", "\""+value+"\"")
+	}
+	project.currentConfig.consultOption("$"+name, value, /*isDefault*/ false)
+
+	out[0].(eval.StringValue).Set(t, value)
+}
+
+// Signature: func Option(name, defaultValue string) string
+func wrapper_Option(t *eval.Thread, in []eval.Value, out []eval.Value) {
+	name := in[0].(eval.StringValue).Get(t)
+	defaultValue := in[1].(eval.StringValue).Get(t)
+
+	if len(name) == 0 {
+		configError(t, errors.New("the name of an option cannot be an empty string"))
+		return
+	}
+
+	value, isDefined := flag_options[name]
+	if !isDefined {
+		value = defaultValue
+	}
+
+	if *flag_debug {
+		println("(read config) option", name, "=", "\""+value+"\"")
+	}
+	project.currentConfig.consultOption(name, value, /*isDefault*/ !isDefined)
+
+	out[0].(eval.StringValue).Set(t, value)
+}
+
+// Options defined on the command-line via "-D key=value"
+type options_flag_t map[string]string
+
+func (o options_flag_t) String() string {
+	var options []string
+	for key, value := range o {
+		options = append(options, key+"="+value)
+	}
+	sort.Strings(options)
+	return strings.Join(options, " ")
+}
+
+func (o options_flag_t) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return errors.New("invalid option \"" + s + "\", expected KEY=VALUE")
+	}
+
+	o[s[0:i]] = s[i+1:]
+	return nil
 }
+
+// Returns the command-line arguments "-NAME=KEY=VALUE" defining the options, sorted by key
+func (o options_flag_t) args(name string) []string {
+	var keys []string
+	for key := range o {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, len(keys))
+	for i, key := range keys {
+		args[i] = "-" + name + "=" + key + "=" + o[key]
+	}
+	return args
+}
+
+var flag_options = make(options_flag_t)
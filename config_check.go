@@ -0,0 +1,199 @@
+package main
+
+import (
+	eval "bitbucket.org/binet/go-eval/pkg/eval"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	pathutil "path"
+	"strings"
+)
+
+// Reports an error found while evaluating a config file.
+// The error is annotated with the position of the function call being evaluated.
+// In check mode, the error is recorded and the evaluation continues.
+func configError(t *eval.Thread, err error) {
+	msg := project.currentCallPosition + ": " + err.Error()
+
+	if project.checkingConfig {
+		project.configDiagnostics = append(project.configDiagnostics, msg)
+		return
+	}
+
+	t.Abort(errors.New(msg))
+}
+
+// Reports an error found after all config files have been evaluated.
+// In check mode, the error is recorded and nil is returned.
+func deferredConfigError(pos string, err error) error {
+	msg := pos + ": " + err.Error()
+
+	if project.checkingConfig {
+		project.configDiagnostics = append(project.configDiagnostics, msg)
+		return nil
+	}
+
+	return errors.New(msg)
+}
+
+// ===============================
+// Positions of config calls
+// ===============================
+
+// The positions of function calls in a script file
+type script_calls_t struct {
+	sites map[string][]token.Position // Function name --> positions of calls, in source order
+}
+
+type call_visitor_t struct {
+	fileSet *token.FileSet
+	sites   map[string][]token.Position
+}
+
+func (v *call_visitor_t) Visit(node ast.Node) ast.Visitor {
+	if call, isCall := node.(*ast.CallExpr); isCall {
+		if ident, isIdent := call.Fun.(*ast.Ident); isIdent {
+			pos := v.fileSet.Position(call.Pos())
+			// The 1st line of the parsed source code is the artificial function header
+			pos.Line -= 1
+			v.sites[ident.Name] = append(v.sites[ident.Name], pos)
+		}
+	}
+
+	return v
+}
+
+// Finds the positions of all calls in the script.
+// The script is parsed as the body of a Go function.
+// If the script cannot be parsed, positions of calls will be unknown.
+func findCallSites(path, sourceCode string) *script_calls_t {
+	calls := &script_calls_t{
+		sites: make(map[string][]token.Position),
+	}
+
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, path, "package config; func _() {\n"+sourceCode+"\n}", /*mode*/ 0)
+	if err != nil {
+		// Syntax errors will be reported by the interpreter
+		return calls
+	}
+
+	v := &call_visitor_t{fileSet: fileSet, sites: calls.sites}
+	ast.Walk(v, file)
+
+	return calls
+}
+
+// Returns a function which records the position of the call and then calls 'fn'.
+//
+// The interpreter does not provide the position of the call being evaluated.
+// If the script contains a single call to the function, the position is exact.
+// Otherwise, it is unknown which of the calls is being evaluated
+// (calls can be skipped by conditionals or repeated by loops),
+// so only the path of the script is reported.
+func traced(name string, fn func(*eval.Thread, []eval.Value, []eval.Value)) func(*eval.Thread, []eval.Value, []eval.Value) {
+	return func(t *eval.Thread, in []eval.Value, out []eval.Value) {
+		path := project.includeStack[len(project.includeStack)-1]
+
+		project.currentCallPosition = path
+		if calls, ok := project.scriptCalls[path]; ok {
+			if sites := calls.sites[name]; len(sites) == 1 {
+				project.currentCallPosition = sites[0].String()
+			}
+		}
+
+		fn(t, in, out)
+	}
+}
+
+// ===============================
+// Checks performed after reading
+// ===============================
+
+// A check performed by "goam check-config" after all config files have been read
+type config_check_t struct {
+	pos   string
+	check func(root *dir_t) error
+}
+
+// Registers a check which will be performed by "goam check-config"
+func addConfigCheck(check func(root *dir_t) error) {
+	if project.checkingConfig {
+		project.deferredConfigChecks = append(project.deferredConfigChecks, config_check_t{project.currentCallPosition, check})
+	}
+}
+
+func checkConfig([]string) error {
+	project.checkingConfig = true
+
+	root, err := readDir()
+	if err != nil {
+		return err
+	}
+
+	for _, check := range project.deferredConfigChecks {
+		err = check.check(root)
+		if err != nil {
+			project.configDiagnostics = append(project.configDiagnostics, check.pos+": "+err.Error())
+		}
+	}
+
+	if len(project.configDiagnostics) > 0 {
+		for _, msg := range project.configDiagnostics {
+			fmt.Fprintf(os.Stderr, "%s\n", msg)
+		}
+		return errors.New(fmt.Sprintf("found %d problem(s) in configuration files", len(project.configDiagnostics)))
+	}
+
+	if *flag_verbose {
+		fmt.Fprintf(os.Stdout, "no problems found\n")
+	}
+
+	return nil
+}
+
+// Returns the directory at 'path' in the filesystem model, or nil
+func lookupDir(root *dir_t, path string) *dir_t {
+	object := root.getObject_orNil(strings.Split(pathutil.Clean(path), "/"))
+	if dir, isDir := object.(*dir_t); isDir {
+		return dir
+	}
+
+	return nil
+}
+
+// Checks that 'InstallExecutable' refers to an executable GOAM knows how to build
+func checkInstalledExecutable(root *dir_t, srcPath string) error {
+	if _, defined := project.executable2sources[srcPath]; defined {
+		return nil
+	}
+
+	dirPath, name := pathutil.Split(srcPath)
+	if len(dirPath) == 0 {
+		dirPath = "."
+	}
+
+	dir := lookupDir(root, dirPath)
+	if dir != nil {
+		if dir.makefile_orNil != nil {
+			// The executable might be built by the Makefile
+			return nil
+		}
+
+		if name == defaultExeName {
+			return nil
+		}
+
+		if project.zeroConfig {
+			baseName, err := dir.baseName()
+			if (err == nil) && (name == baseName) {
+				return nil
+			}
+		}
+	}
+
+	return errors.New("cannot install \"" + srcPath + "\": no such executable has been defined")
+}
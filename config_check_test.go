@@ -0,0 +1,42 @@
+package main
+
+import (
+	eval "bitbucket.org/binet/go-eval/pkg/eval"
+	"testing"
+)
+
+func TestTracedCallPositions(t *testing.T) {
+	_, leave := enterTempDir(t)
+	defer leave()
+
+	const path = "sub/GOAM.conf"
+	src := "Package(\"a\")\n" +
+		"if Option(\"x\") != \"\" {\n" +
+		"\tIgnoreDir(\"x\")\n" +
+		"} else {\n" +
+		"\tIgnoreDir(\"y\")\n" +
+		"}\n"
+	project.includeStack = []string{path}
+	project.scriptCalls[path] = findCallSites(path, src)
+
+	positionOf := func(name string) string {
+		var pos string
+		fn := traced(name, func(*eval.Thread, []eval.Value, []eval.Value) { pos = project.currentCallPosition })
+		fn(nil, nil, nil)
+		return pos
+	}
+
+	if pos := positionOf("Package"); pos != path+":1:1" {
+		t.Errorf("expected the position %s:1:1, got %s", path, pos)
+	}
+	if pos := positionOf("Option"); pos != path+":2:4" {
+		t.Errorf("expected the position %s:2:4, got %s", path, pos)
+	}
+
+	// It is unknown which of the calls is evaluated
+	for i := 0; i < 2; i++ {
+		if pos := positionOf("IgnoreDir"); pos != path {
+			t.Errorf("expected the position %s without a line, got %s", path, pos)
+		}
+	}
+}
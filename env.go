@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+)
+
+type tool_info_t struct {
+	role     string
+	exe      *Executable
+	required bool // Whether the tool is part of the Go toolchain
+}
+
+func env([]string) error {
+	w := os.Stdout
+
+	fmt.Fprintf(w, "GOROOT:               %s\n", goRoot)
+	{
+		gobin := *flag_gobin
+		if len(gobin) == 0 {
+			gobin = os.Getenv("GOBIN")
+		}
+		if len(gobin) == 0 {
+			gobin = "(not set, using $GOROOT/bin)"
+		}
+		fmt.Fprintf(w, "GOBIN:                %s\n", gobin)
+	}
+	fmt.Fprintf(w, "GOOS:                 %s\n", runtime.GOOS)
+	fmt.Fprintf(w, "GOARCH:               %s\n", runtime.GOARCH)
+	fmt.Fprintf(w, "libInstallRoot:       %s\n", libInstallRoot)
+	fmt.Fprintf(w, "remotePkgInstallRoot: %s\n", remotePkgInstallRoot)
+	fmt.Fprintf(w, "exeInstallDir:        %s\n", exeInstallDir)
+	fmt.Fprintf(w, "\n")
+
+	tools := []tool_info_t{
+		{"compiler", goCompiler_exe, true},
+		{"archiver", goArchiver_exe, true},
+		{"linker", goLinker_exe, true},
+		{"make", make_exe, false},
+		{"gofmt", gofmt_exe, false},
+		{"yacc", yacc_exe, false},
+		{"protoc", protoc_exe, false},
+		{"cp", cp_exe, false},
+		{"git", git_exe, false},
+		{"hg", hg_exe, false},
+		{"svn", svn_exe, false},
+		{"bzr", bzr_exe, false},
+	}
+
+	missingRequiredTools := printTools(w, tools)
+
+	fmt.Fprintf(w, "\n")
+	if *flag_gcc {
+		fmt.Fprintf(w, "Compiler version: unknown (gccgo)\n")
+	} else if goCompiler_exe.lookup() == nil {
+		version, err := getGoCompilerVersion()
+		if err != nil {
+			fmt.Fprintf(w, "Compiler version: %s\n", err)
+		} else {
+			fmt.Fprintf(w, "Compiler version: %d\n", version)
+		}
+	} else {
+		fmt.Fprintf(w, "Compiler version: unknown (compiler not found)\n")
+	}
+
+	if missingRequiredTools {
+		return errors.New("the Go toolchain is incomplete")
+	}
+
+	return nil
+}
+
+// Prints the full paths of the tools.
+// Returns true if some of the required tools are missing.
+func printTools(w io.Writer, tools []tool_info_t) bool {
+	missingRequiredTools := false
+
+	fmt.Fprintf(w, "Tools:\n")
+	for _, tool := range tools {
+		fmt.Fprintf(w, "    %-9s %-7s ", tool.role+":", tool.exe.name)
+
+		err := tool.exe.lookup()
+		if err == nil {
+			fmt.Fprintf(w, "%s\n", tool.exe.fullPath)
+		} else {
+			if tool.required {
+				missingRequiredTools = true
+				fmt.Fprintf(w, "NOT FOUND (required)\n")
+			} else {
+				fmt.Fprintf(w, "NOT FOUND\n")
+			}
+		}
+	}
+
+	return missingRequiredTools
+}
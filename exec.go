@@ -123,6 +123,7 @@ type Executable struct {
 type RunFlags struct {
 	stdin, stdout, stderr *os.File
 	dontPrintCmd          bool
+	env                   []string // Environment of the process, nil means the environment of GOAM
 }
 
 func (e *Executable) runSimply(argv []string, dir string, dontPrintCmd bool) error {
@@ -136,6 +137,20 @@ func (e *Executable) runSimply(argv []string, dir string, dontPrintCmd bool) err
 	return e.run_lowLevel(argv, dir, flags)
 }
 
+// Same as 'runSimply', but the environment of the process is extended by 'env'.
+// Each item of 'env' has the format "NAME=VALUE".
+func (e *Executable) runWithEnv(argv []string, dir string, env []string) error {
+	flags := RunFlags{
+		stdin:        os.Stdin,
+		stdout:       os.Stdout,
+		stderr:       os.Stderr,
+		dontPrintCmd: false,
+		env:          append(os.Environ(), env...),
+	}
+
+	return e.run_lowLevel(argv, dir, flags)
+}
+
 // Runs 'e' as separate process, waits until it finishes,
 // and returns the data the process sent to its output(s).
 // The argument 'in' comprises the command's input.
@@ -253,9 +268,8 @@ func (e *Executable) run(argv []string, dir string, in string, mergeStdoutAndStd
 	return stdout, stderr, nil
 }
 
-// Runs 'e' as a separate process and waits until it finishes
-func (e *Executable) run_lowLevel(argv []string, dir string, flags RunFlags) error {
-	// Resolve 'e.fullpath' (if not resolved yet)
+// Resolves 'e.fullPath' (if not resolved yet)
+func (e *Executable) lookup() error {
 	if len(e.fullPath) == 0 {
 		if (e.noLookup == false) || !strings.HasPrefix(e.name, "./") {
 			var err error
@@ -269,6 +283,40 @@ func (e *Executable) run_lowLevel(argv []string, dir string, flags RunFlags) err
 		}
 	}
 
+	return nil
+}
+
+// Checks whether all of the specified executables can be found.
+// All missing executables are reported by a single error.
+func lookupExecutables(exes []*Executable) error {
+	var missing []string
+	seen := make(map[string]byte)
+	for _, e := range exes {
+		if _, alreadySeen := seen[e.name]; alreadySeen {
+			continue
+		}
+		seen[e.name] = 0
+
+		if e.lookup() != nil {
+			missing = append(missing, e.name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return errors.New("unable to find the following tools in $PATH: " + strings.Join(missing, ", ") +
+			" (run \"goam env\" for details)")
+	}
+
+	return nil
+}
+
+// Runs 'e' as a separate process and waits until it finishes
+func (e *Executable) run_lowLevel(argv []string, dir string, flags RunFlags) error {
+	err := e.lookup()
+	if err != nil {
+		return err
+	}
+
 	if dir == "." {
 		dir = ""
 	}
@@ -283,6 +331,7 @@ func (e *Executable) run_lowLevel(argv []string, dir string, flags RunFlags) err
 
 	procAttr := os.ProcAttr{
 		Dir:   dir,
+		Env:   flags.env,
 		Files: []*os.File{flags.stdin, flags.stdout, flags.stderr},
 	}
 	process, err := os.StartProcess(e.fullPath, argv, &procAttr)
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Enumeration of the phases of identifying files in a directory
+const (
+	// Files identified before the config file of the directory is evaluated
+	FILE_HANDLER_BEFORE_CONFIG = iota
+
+	// Files identified after the config file of the directory is evaluated
+	FILE_HANDLER_AFTER_CONFIG
+)
+
+// A handler of a file type.
+// The handler identifies files and creates the objects representing them.
+type file_handler_t struct {
+	name        string // The name used by 'EnableFileTypes' and 'DisableFileTypes'
+	description string // Printed in debug mode
+	phase       int    // One of: FILE_HANDLER_BEFORE_CONFIG, FILE_HANDLER_AFTER_CONFIG
+
+	// Whether config files can enable or disable the handler.
+	// Only handlers in phase FILE_HANDLER_AFTER_CONFIG can be optional.
+	optional       bool
+	enabledDefault bool // Whether an optional handler is enabled if no config file says otherwise
+
+	// Returns whether the file is handled by this handler
+	match func(fileName string, fi os.FileInfo) bool
+
+	// Creates the object representing the file
+	create func(entry entry_t, parent *dir_t) (object_t, error)
+
+	// Infers objects from all files in a directory identified by this handler.
+	// Called once per directory, in addition to 'InferObjects' of each object.
+	inferDir_orNil func(dir *dir_t, objects []object_t, updateTests bool) error
+}
+
+// All file handlers, in the order in which they are tried.
+// A file is handled by the first matching handler.
+var fileHandlers = []*file_handler_t{
+	goTest_fileHandler,
+	goFile_fileHandler,
+	config_fileHandler,
+	makefile_fileHandler,
+	compilationUnit_fileHandler,
+	library_fileHandler,
+	yacc_fileHandler,
+	proto_fileHandler,
+	executable_fileHandler,
+}
+
+// Returns the handler with the specified name, or nil
+func lookupFileHandler(name string) *file_handler_t {
+	for _, h := range fileHandlers {
+		if h.name == name {
+			return h
+		}
+	}
+
+	return nil
+}
+
+// Returns the names of optional handlers
+func optionalFileHandlerNames() []string {
+	var names []string
+	for _, h := range fileHandlers {
+		if h.optional {
+			names = append(names, h.name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Returns whether the handler is enabled in directory 'dir'
+func (h *file_handler_t) enabledIn(dir *dir_t) bool {
+	if !h.optional {
+		return true
+	}
+
+	if enabled, overridden := dir.settings().fileTypes[h.name]; overridden {
+		return enabled
+	}
+
+	return h.enabledDefault
+}
+
+// Identifies the file using the handlers of the specified phase.
+// Returns nil if the file is not handled by any of the handlers.
+func identifyFile(path string, fi os.FileInfo, parent *dir_t, phase int) (object_t, error) {
+	for _, h := range fileHandlers {
+		if (h.phase != phase) || !h.match(fi.Name(), fi) {
+			continue
+		}
+
+		if !h.enabledIn(parent) {
+			if *flag_debug {
+				println("disabled file type \""+h.name+"\":", path)
+			}
+			return nil, nil
+		}
+
+		if *flag_debug {
+			println(h.description+":", path)
+		}
+
+		object, err := h.create(new_entry(path, fi), parent)
+		if err != nil {
+			return nil, err
+		}
+
+		if h.inferDir_orNil != nil {
+			if parent.handledObjects == nil {
+				parent.handledObjects = make(map[*file_handler_t][]object_t)
+			}
+			parent.handledObjects[h] = append(parent.handledObjects[h], object)
+		}
+
+		return object, nil
+	}
+
+	return nil, nil
+}
+
+// Calls the directory inference rules of file handlers
+func (d *dir_t) inferObjectsFromHandlers(updateTests bool) error {
+	for _, h := range fileHandlers {
+		if objects, ok := d.handledObjects[h]; ok {
+			err := h.inferDir_orNil(d, objects, updateTests)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Parses a space-separated list of names of optional file handlers
+func parseFileHandlerNames(names string) ([]string, error) {
+	list := strings.Fields(names)
+	if len(list) == 0 {
+		return nil, errors.New("empty list of file types")
+	}
+
+	for _, name := range list {
+		h := lookupFileHandler(name)
+		if (h == nil) || !h.optional {
+			return nil, errors.New("unknown optional file type \"" + name + "\"" +
+				" (known optional file types: " + strings.Join(optionalFileHandlerNames(), ", ") + ")")
+		}
+	}
+
+	return list, nil
+}
+
+// =============
+// Core handlers
+// =============
+
+var goTest_fileHandler = &file_handler_t{
+	name:        "go-test",
+	description: "go test",
+	phase:       FILE_HANDLER_BEFORE_CONFIG,
+	match: func(fileName string, fi os.FileInfo) bool {
+		return strings.HasSuffix(fileName, "_test.go")
+	},
+	create: func(entry entry_t, parent *dir_t) (object_t, error) {
+		return new_go_test(entry, parent), nil
+	},
+}
+
+var goFile_fileHandler = &file_handler_t{
+	name:        "go",
+	description: "go source code",
+	phase:       FILE_HANDLER_BEFORE_CONFIG,
+	match: func(fileName string, fi os.FileInfo) bool {
+		return strings.HasSuffix(fileName, ".go")
+	},
+	create: func(entry entry_t, parent *dir_t) (object_t, error) {
+		return new_go_file(entry, parent), nil
+	},
+}
+
+var config_fileHandler = &file_handler_t{
+	name:        "config",
+	description: "config file",
+	phase:       FILE_HANDLER_BEFORE_CONFIG,
+	match: func(fileName string, fi os.FileInfo) bool {
+		return (len(fileName) == len(configFileName)) && (strings.ToLower(fileName) == strings.ToLower(configFileName))
+	},
+	create: func(entry entry_t, parent *dir_t) (object_t, error) {
+		return new_config_file(entry, parent)
+	},
+}
+
+var makefile_fileHandler = &file_handler_t{
+	name:        "makefile",
+	description: "makefile",
+	phase:       FILE_HANDLER_BEFORE_CONFIG,
+	match: func(fileName string, fi os.FileInfo) bool {
+		return fileName == "Makefile"
+	},
+	create: func(entry entry_t, parent *dir_t) (object_t, error) {
+		return new_makefile(entry, parent)
+	},
+}
+
+var compilationUnit_fileHandler = &file_handler_t{
+	name:        "compilation-unit",
+	description: "compilation unit",
+	phase:       FILE_HANDLER_BEFORE_CONFIG,
+	match: func(fileName string, fi os.FileInfo) bool {
+		return isCompilationUnit(fileName)
+	},
+	create: func(entry entry_t, parent *dir_t) (object_t, error) {
+		return new_compilation_unit(entry, parent), nil
+	},
+}
+
+var library_fileHandler = &file_handler_t{
+	name:        "library",
+	description: "library",
+	phase:       FILE_HANDLER_BEFORE_CONFIG,
+	match: func(fileName string, fi os.FileInfo) bool {
+		return strings.HasSuffix(fileName, ".a")
+	},
+	create: func(entry entry_t, parent *dir_t) (object_t, error) {
+		return new_library(entry, parent), nil
+	},
+}
+
+// Any file with the executable bit is treated as an executable.
+// This handler is tried last, so that other handlers can claim such files.
+var executable_fileHandler = &file_handler_t{
+	name:        "executable",
+	description: "executable",
+	phase:       FILE_HANDLER_AFTER_CONFIG,
+	match: func(fileName string, fi os.FileInfo) bool {
+		return (fi.Mode().Perm() & 0100) != 0
+	},
+	create: func(entry entry_t, parent *dir_t) (object_t, error) {
+		return new_executable(entry, parent), nil
+	},
+}
+
+// ============
+// nop_object_t
+// ============
+
+// An object which does nothing.
+// Object types can embed this type and implement only the methods they need.
+type nop_object_t struct {
+	entry_t
+}
+
+func (o *nop_object_t) UpdateFileSystemModel() {
+	o.UpdateFileInfo()
+}
+
+func (o *nop_object_t) InferObjects(updateTests bool) error {
+	return nil
+}
+
+func (o *nop_object_t) PrintDependencies(w io.Writer) {
+	return
+}
+
+func (o *nop_object_t) Info(info *info_t) {
+	return
+}
+
+func (o *nop_object_t) Make() error {
+	return nil
+}
+
+func (o *nop_object_t) MakeTests() error {
+	return nil
+}
+
+func (o *nop_object_t) RunTests(testPattern, benchPattern string, errors *[]error) {
+	return
+}
+
+func (o *nop_object_t) Clean() error {
+	return nil
+}
+
+func (o *nop_object_t) GoFmt(files *[]string) error {
+	return nil
+}
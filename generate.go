@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	pathutil "path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const generateDirectivePrefix = "//goam:generate"
+
+// A comment like "//goam:generate -output=FILE1,FILE2 CMD ARGS..." in a Go file
+type go_generate_directive_t struct {
+	line    int      // The line number of the comment
+	outputs []string // Relative to the directory of the Go file
+	argv    []string
+}
+
+// A command defined by a '//goam:generate' directive
+type go_generator_t struct {
+	file    string // The path of the Go file containing the directive
+	line    int
+	command *command_t
+}
+
+// Finds the '//goam:generate' directives in the source code.
+// Unlike build constraints, the directives can be anywhere in the file,
+// thus the source code is scanned line by line instead of walking the AST.
+func parseGenerateDirectives(filePath string, src []byte) ([]*go_generate_directive_t, error) {
+	var directives []*go_generate_directive_t
+	for i, line := range bytes.Split(src, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte(generateDirectivePrefix)) {
+			continue
+		}
+
+		rest := string(bytes.TrimRight(line[len(generateDirectivePrefix):], " \t\r"))
+		if (len(rest) > 0) && (rest[0] != ' ') && (rest[0] != '\t') {
+			// Something like "//goam:generated"
+			continue
+		}
+
+		lineNumber := i + 1
+		pos := filePath + ":" + strconv.Itoa(lineNumber)
+
+		words, err := splitQuotedWords(rest)
+		if err != nil {
+			return nil, errors.New(pos + ": " + err.Error())
+		}
+
+		var outputs []string
+		for (len(words) > 0) && strings.HasPrefix(words[0], "-output=") {
+			for _, output := range strings.Split(words[0][len("-output="):], ",") {
+				if len(output) == 0 {
+					return nil, errors.New(pos + ": empty output file name")
+				}
+				if pathutil.IsAbs(output) {
+					return nil, errors.New(pos + ": output \"" + output + "\" is an absolute path")
+				}
+				output = pathutil.Clean(output)
+				if (output == "..") || strings.HasPrefix(output, "../") {
+					return nil, errors.New(pos + ": output \"" + output + "\" is outside of the directory of the Go file")
+				}
+				outputs = append(outputs, output)
+			}
+			words = words[1:]
+		}
+
+		if len(words) == 0 {
+			return nil, errors.New(pos + ": empty command")
+		}
+
+		directives = append(directives, &go_generate_directive_t{
+			line:    lineNumber,
+			outputs: outputs,
+			argv:    words,
+		})
+	}
+
+	return directives, nil
+}
+
+// Creates the commands defined by the '//goam:generate' directives in the file.
+// A command is re-run when the file is newer than the outputs of the command.
+func (f *go_file_t) inferGenerators(contents *go_file_contents_t) error {
+	if f.generator_orNil != nil {
+		// Directives in generated files are ignored
+		return nil
+	}
+
+	for _, directive := range contents.generateDirectives {
+		outputs := make([]string, len(directive.outputs))
+		for i, output := range directive.outputs {
+			outputs[i] = pathutil.Join(f.parent.path, output)
+		}
+
+		command, err := new_command(f.parent, outputs, /*inputs*/ []string{f.path}, directive.argv)
+		if err != nil {
+			return errors.New(f.path + ":" + strconv.Itoa(directive.line) + ": " + err.Error())
+		}
+		command.env = []string{
+			"GOFILE=" + f.name,
+			"GOPACKAGE=" + contents.packageName,
+		}
+
+		project.goGenerators = append(project.goGenerators, &go_generator_t{
+			file:    f.path,
+			line:    directive.line,
+			command: command,
+		})
+	}
+
+	return nil
+}
+
+type goGenerators_byPosition []*go_generator_t
+
+func (l goGenerators_byPosition) Len() int      { return len(l) }
+func (l goGenerators_byPosition) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l goGenerators_byPosition) Less(i, j int) bool {
+	if l[i].file != l[j].file {
+		return l[i].file < l[j].file
+	}
+	return l[i].line < l[j].line
+}
+
+// Runs all generators, in the order of the directives in the files
+func runGoGenerators() error {
+	generators := make([]*go_generator_t, len(project.goGenerators))
+	copy(generators, project.goGenerators)
+	sort.Sort(goGenerators_byPosition(generators))
+
+	for _, generator := range generators {
+		err := generator.command.run()
+		if err != nil {
+			return errors.New(generator.file + ":" + strconv.Itoa(generator.line) + ": " + err.Error())
+		}
+		generator.command.built = true
+	}
+
+	return nil
+}
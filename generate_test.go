@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestParseGenerateDirectivesOutputs(t *testing.T) {
+	src := "package a\n\n//goam:generate -output=gen.go,sub/../b.go ./gen.sh\n"
+	directives, err := parseGenerateDirectives("a/a.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(directives) != 1) || (strings.Join(directives[0].outputs, ",") != "gen.go,b.go") {
+		t.Fatalf("unexpected directives: %v", directives)
+	}
+
+	for _, output := range []string{"/tmp/x.go", "../x.go", "../../etc/x.go", "sub/../../x.go", ".."} {
+		src := "package a\n\n//goam:generate -output=" + output + " ./gen.sh\n"
+		_, err := parseGenerateDirectives("a/a.go", []byte(src))
+		if (err == nil) || !strings.HasPrefix(err.Error(), "a/a.go:3: output") {
+			t.Errorf("%s: expected an error, got %v", output, err)
+		}
+	}
+}
+
+// A generator in a subdirectory runs a script relative to the directory of the Go file
+func TestGoGeneratorInSubdirectory(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	savedGenerateMissingFiles := generateMissingFiles
+	generateMissingFiles = true
+	defer func() { generateMissingFiles = savedGenerateMissingFiles }()
+
+	writeTestFile(t, "sub/a/a.go", "package a\n\n//goam:generate -output=gen.go ./gen.sh\n")
+	writeTestScript(t, "sub/a/gen.sh", "echo \"package a // $GOFILE\" > gen.go\n")
+
+	*flag_srcroot = dir
+	_, err := boot( /*updateTests*/ false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = runGoGenerators()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ioutil.ReadFile("sub/a/gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "package a // a.go\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
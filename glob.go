@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	pathutil "path"
+	"strings"
+)
+
+// Returns whether 'pattern' contains any special characters
+// recognized by 'matchGlob'
+func isGlobPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// Reports whether the slash-separated 'path' matches the 'pattern'.
+// Each path element is matched by 'path.Match', except for the pattern
+// element "**" which matches zero or more path elements.
+func matchGlob(pattern, path string) (bool, error) {
+	return matchGlob_elements(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchGlob_elements(pattern, path []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try to match the rest of the pattern against every suffix of 'path'
+			for i := 0; i <= len(path); i++ {
+				matched, err := matchGlob_elements(pattern[1:], path[i:])
+				if err != nil {
+					return false, err
+				}
+				if matched {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+
+		if len(path) == 0 {
+			return false, nil
+		}
+
+		matched, err := pathutil.Match(pattern[0], path[0])
+		if err != nil {
+			return false, errors.New("invalid pattern \"" + strings.Join(pattern, "/") + "\"")
+		}
+		if !matched {
+			return false, nil
+		}
+
+		pattern = pattern[1:]
+		path = path[1:]
+	}
+
+	return (len(path) == 0), nil
+}
+
+// Returns the path of 'path' relative to directory 'base'.
+// The second return value is false if 'path' is not within 'base'.
+func relativePath(base, path string) (string, bool) {
+	switch {
+	case path == base:
+		return "", true
+	case base == ".":
+		return path, true
+	case strings.HasPrefix(path, base+"/"):
+		return path[len(base)+1:], true
+	}
+
+	return "", false
+}
+
+// Returns all files in the directory 'd' and its sub-directories
+// whose paths (relative to 'd') match the 'pattern'.
+// Temporary directories are skipped.
+func (d *dir_t) globFiles(pattern string) ([]object_t, error) {
+	return d.globFiles_internal(pattern, d.path)
+}
+
+func (d *dir_t) globFiles_internal(pattern, base string) ([]object_t, error) {
+	var result []object_t
+
+	for _, object := range d.objects {
+		if subdir, isDir := object.(*dir_t); isDir {
+			if subdir.isTemporary() {
+				continue
+			}
+
+			objects, err := subdir.globFiles_internal(pattern, base)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, objects...)
+		} else {
+			relPath, _ := relativePath(base, object.Path())
+			matched, err := matchGlob(pattern, relPath)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				result = append(result, object)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Expands a list of file patterns relative to directory 'd'.
+// A pattern starting with "!" removes the matching files from the result.
+// Each pattern is checked by 'cleanAndCheckPath'.
+// A pattern which does not match any file is an error.
+// The returned paths are relative to 'd'.
+func (d *dir_t) expandFilePatterns(patterns []string) ([]string, error) {
+	var files []string
+
+	for _, pattern := range patterns {
+		exclude := strings.HasPrefix(pattern, "!")
+		if exclude {
+			pattern = pattern[1:]
+		}
+
+		pattern, err := cleanAndCheckPath(nil, pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		var matches []string
+		if isGlobPattern(pattern) {
+			objects, err := d.globFiles(pattern)
+			if err != nil {
+				return nil, err
+			}
+			for _, object := range objects {
+				relPath, _ := relativePath(d.path, object.Path())
+				matches = append(matches, relPath)
+			}
+		} else {
+			if d.getObject_orNil(strings.Split(pattern, "/")) != nil {
+				matches = []string{pattern}
+			}
+		}
+
+		if len(matches) == 0 {
+			if exclude {
+				return nil, errors.New("exclusion \"!" + pattern + "\" does not match any files")
+			}
+			return nil, errors.New("pattern \"" + pattern + "\" does not match any files")
+		}
+
+		if !exclude {
+			files = append(files, matches...)
+		} else {
+			excluded := make(map[string]byte)
+			for _, match := range matches {
+				excluded[match] = 0
+			}
+
+			i := 0
+			for _, file := range files {
+				if _, isExcluded := excluded[file]; !isExcluded {
+					files[i] = file
+					i++
+				}
+			}
+			files = files[0:i]
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("the patterns " + strings.Join(patterns, " ") + " do not select any files")
+	}
+
+	// Remove duplicates
+	{
+		seen := make(map[string]byte)
+		i := 0
+		for _, file := range files {
+			if _, alreadySeen := seen[file]; !alreadySeen {
+				seen[file] = 0
+				files[i] = file
+				i++
+			}
+		}
+		files = files[0:i]
+	}
+
+	return files, nil
+}
+
+// Returns whether a list of files contains patterns
+func containsPatterns(items []string) bool {
+	for _, item := range items {
+		if isGlobPattern(item) || strings.HasPrefix(item, "!") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+)
+
+// Enumeration of hook kinds
+const (
+	HOOK_BEFORE_BUILD = iota
+	HOOK_AFTER_BUILD
+	HOOK_AFTER_TEST
+	NUM_HOOK_KINDS
+)
+
+var hookNames = [NUM_HOOK_KINDS]string{"BeforeBuild", "AfterBuild", "AfterTest"}
+
+// Describes the artifact passed to hooks via environment variables
+type hook_artifact_t struct {
+	path       string // GOAM_ARTIFACT
+	kind       string // GOAM_ARTIFACT_KIND: "library", "executable" or "test"
+	importPath string // GOAM_IMPORT_PATH, empty for executables
+	testFailed bool   // GOAM_TEST_STATUS, only used by 'AfterTest' hooks
+}
+
+// Returns the directory containing the config file which defines the hooks
+// applicable to artifacts in directory 'd'. Artifacts in temporary directories
+// (such as libraries in "_obj") belong to the parent of the temporary directory.
+func (d *dir_t) hooksDir() *dir_t {
+	for dir := d; dir != nil; dir = dir.parent_orNil {
+		if !dir.shouldRemove() {
+			return dir
+		}
+	}
+
+	return d.root()
+}
+
+// Runs the hooks of the specified kind defined by the config file
+// in the directory owning the artifact. A hook which fails
+// (exits with a non-zero status) fails the build.
+func runHooks(kind int, artifactDir *dir_t, artifact *hook_artifact_t) error {
+	dir := artifactDir.hooksDir()
+	if dir.config_orNil == nil {
+		return nil
+	}
+
+	hooks := dir.config_orNil.hooks[kind]
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	// The path of the artifact relative to the directory in which the hook runs
+	path := artifact.path
+	if relPath, isWithin := relativePath(dir.path, path); isWithin && (len(relPath) > 0) {
+		path = relPath
+	}
+
+	env := []string{
+		"GOAM_ARTIFACT=" + path,
+		"GOAM_ARTIFACT_KIND=" + artifact.kind,
+		"GOAM_IMPORT_PATH=" + artifact.importPath,
+		"GOOS=" + runtime.GOOS,
+		"GOARCH=" + runtime.GOARCH,
+	}
+	if kind == HOOK_AFTER_TEST {
+		if artifact.testFailed {
+			env = append(env, "GOAM_TEST_STATUS=fail")
+		} else {
+			env = append(env, "GOAM_TEST_STATUS=pass")
+		}
+	}
+
+	for _, argv := range hooks {
+		if *flag_debug {
+			println(hookNames[kind]+":", artifact.path)
+		}
+
+		exe := &Executable{name: argv[0], noLookup: strings.HasPrefix(argv[0], "./")}
+		err := exe.runWithEnv(argv, /*dir*/ dir.path, env)
+		if err != nil {
+			return errors.New(dir.config_orNil.Path() + ": " + hookNames[kind] + " hook \"" +
+				strings.Join(argv, " ") + "\" failed for \"" + artifact.path + "\": " + err.Error())
+		}
+	}
+
+	return nil
+}
@@ -10,15 +10,12 @@ type package_resolution_t struct {
 	includePath *dir_t
 }
 
-var importPathResolutionTable = make(map[string]*package_resolution_t)
-var importPathResolutionTable_test = make(map[string]*package_resolution_t)
-
 func mapImportPath(importPath string, lib *library_t, includePath *dir_t, test bool) error {
 	var table map[string]*package_resolution_t
 	if !test {
-		table = importPathResolutionTable
+		table = project.importPathResolutionTable
 	} else {
-		table = importPathResolutionTable_test
+		table = project.importPathResolutionTable_test
 	}
 
 	if pkg, alreadyMapped := table[importPath]; alreadyMapped {
@@ -40,9 +37,9 @@ func mapImportPath(importPath string, lib *library_t, includePath *dir_t, test b
 func resolvePackage(importPath string, test bool) (*package_resolution_t, error) {
 	var table map[string]*package_resolution_t
 	if !test {
-		table = importPathResolutionTable
+		table = project.importPathResolutionTable
 	} else {
-		table = importPathResolutionTable_test
+		table = project.importPathResolutionTable_test
 	}
 
 	if pkg, ok := table[importPath]; ok {
@@ -67,7 +64,26 @@ func resolvePackage(importPath string, test bool) (*package_resolution_t, error)
 		dir = ""
 	}
 
-	if !fileExists(pathutil.Join(libInstallRoot, dir, base+".a")) {
+	// A replaced package is resolved only from the libraries built from the local directory.
+	// The libraries are found via the "-I" and "-L" options, see 'libSearchRoots'.
+	if remotePackage := replacedPackage(importPath); remotePackage != nil {
+		if !fileExists(pathutil.Join(remotePackage.replacementLibRoot(), dir, base+".a")) {
+			// The library is installed from the local directory by "goam install-deps"
+			return nil, errors.New("failed to resolve package \"" + importPath + "\"" +
+				" (replaced by the local directory \"" + remotePackage.repository.Path() + "\")")
+		}
+		return nil, nil
+	}
+
+	// Libraries in 'libSearchRoots' are found via the "-I" and "-L" options
+	// passed to the compiler and the linker for all packages
+	for _, root := range libSearchRoots() {
+		if fileExists(pathutil.Join(root, dir, base+".a")) {
+			return nil, nil
+		}
+	}
+
+	if !fileExists(pathutil.Join(goRootLibDir, dir, base+".a")) {
 		return nil, errors.New("failed to resolve package \"" + importPath + "\"")
 	}
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 )
 
 type info_t struct {
@@ -15,6 +16,12 @@ type info_t struct {
 
 	// Set of executables. (The values of the map have no meaning.)
 	tests map[*executable_t]byte
+
+	// Set of config files consulting options. (The values of the map have no meaning.)
+	configs map[*config_file_t]byte
+
+	// Set of commands. (The values of the map have no meaning.)
+	commands map[*command_t]byte
 }
 
 func new_info() *info_t {
@@ -22,6 +29,8 @@ func new_info() *info_t {
 		libs:        make(map[*library_t]byte),
 		executables: make(map[*executable_t]byte),
 		tests:       make(map[*executable_t]byte),
+		configs:     make(map[*config_file_t]byte),
+		commands:    make(map[*command_t]byte),
 	}
 }
 
@@ -82,16 +91,40 @@ func (info *info_t) Print(w io.Writer) {
 	}
 
 	printExecutables(w, "Executables", /*allowVerbose*/ true, info.executables, &haveEmptyLine)
+
+	if len(info.commands) > 0 {
+		if !haveEmptyLine {
+			fmt.Fprintf(w, "\n")
+		}
+		fmt.Fprintf(w, "Generated files:\n")
+
+		var lines []string
+		for command := range info.commands {
+			line := strings.Join(command.outputs, " ")
+			if *flag_verbose {
+				line += "  <--  " + strings.Join(command.argv, " ")
+			}
+			lines = append(lines, line)
+		}
+
+		sortAndPrintNames(w, "    ", lines)
+		haveEmptyLine = false
+	}
 	printExecutables(w, "Tests", /*allowVerbose*/ false, info.tests, &haveEmptyLine)
 
-	if len(remotePackages) > 0 {
+	if len(project.remotePackages) > 0 {
 		if !haveEmptyLine {
 			fmt.Fprintf(w, "\n")
 		}
 		fmt.Fprintf(w, "Remote dependencies:\n")
 
-		for _, remotePkg := range remotePackages {
-			fmt.Fprintf(w, "    %s  @%s\n", remotePkg.repository.Path(), remotePkg.repository.KindString())
+		for _, remotePkg := range project.remotePackages {
+			repository := remotePkg.declaredRepository()
+			if remotePkg.isReplaced() {
+				fmt.Fprintf(w, "    %s  @%s  -->  replaced by %s\n", repository.Path(), repository.KindString(), remotePkg.repository.Path())
+			} else {
+				fmt.Fprintf(w, "    %s  @%s\n", repository.Path(), repository.KindString())
+			}
 			haveEmptyLine = false
 
 			if *flag_verbose {
@@ -105,6 +138,52 @@ func (info *info_t) Print(w io.Writer) {
 		}
 	}
 
+	if (len(project.inferredPackages) > 0) || (len(project.inferredExecutables) > 0) {
+		if !haveEmptyLine {
+			fmt.Fprintf(w, "\n")
+		}
+		fmt.Fprintf(w, "Inferred from directory layout:\n")
+
+		var lines []string
+		for dir, importPath := range project.inferredPackages {
+			lines = append(lines, dir+"  -->  package \""+importPath+"\"")
+		}
+		for exe, dir := range project.inferredExecutables {
+			lines = append(lines, dir+"  -->  executable "+exe)
+		}
+
+		sortAndPrintNames(w, "    ", lines)
+		haveEmptyLine = false
+	}
+
+	if *flag_verbose && (len(info.configs) > 0) {
+		if !haveEmptyLine {
+			fmt.Fprintf(w, "\n")
+		}
+		fmt.Fprintf(w, "Options:\n")
+
+		configs_byPath := make(map[string]*config_file_t)
+		paths := make([]string, 0, len(info.configs))
+		for config := range info.configs {
+			configs_byPath[config.path] = config
+			paths = append(paths, config.path)
+		}
+
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			config := configs_byPath[path]
+
+			fmt.Fprintf(w, "    %s\n", path)
+
+			options := make([]string, len(config.consultedOptions))
+			copy(options, config.consultedOptions)
+			sortAndPrintNames(w, "        ", options)
+			fmt.Fprintf(w, "\n")
+			haveEmptyLine = true
+		}
+	}
+
 	if !haveEmptyLine && *flag_timings {
 		fmt.Fprintf(w, "\n")
 	}
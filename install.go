@@ -17,10 +17,6 @@ type installation_command_t interface {
 	Uninstall(root *dir_t) error
 }
 
-var installationCommands []installation_command_t = nil
-var installationCommands_bySrcPath = make(map[string]installation_command_t)
-var installationCommands_packagesByImport = make(map[string]*install_package_t)
-
 // =================
 // install_package_t
 // =================
@@ -34,7 +30,7 @@ func new_installPackage(importPath string) *install_package_t {
 }
 
 func (i *install_package_t) find() (*package_resolution_t, error) {
-	pkg, ok := importPathResolutionTable[i.importPath]
+	pkg, ok := project.importPathResolutionTable[i.importPath]
 	if !ok {
 		return nil, errors.New("unable to install package \"" + i.importPath + "\": no such package")
 	}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The lock file records the revisions of remote packages
+const lockFileName = "GOAM.lock"
+
+// A line in the lock file:
+//
+//   KIND REPOSITORY COMMIT [REVISION] [sha256:CHECKSUM]
+//
+// REVISION is the revision requested by 'RemotePackageRev', if any.
+// CHECKSUM is the checksum of the sources of the commit, see 'hashSourceTree'.
+type lock_entry_t struct {
+	kind           string
+	repositoryPath string
+	commit         string
+	revision       string
+	checksum       string // Without the prefix, or an empty string
+}
+
+const checksumPrefix = "sha256:"
+
+// Returns the line in the lock file
+func (e *lock_entry_t) line() string {
+	line := e.kind + " " + e.repositoryPath + " " + e.commit
+	if len(e.revision) > 0 {
+		line += " " + e.revision
+	}
+	if len(e.checksum) > 0 {
+		line += " " + checksumPrefix + e.checksum
+	}
+
+	return line
+}
+
+// Reads the lock file in the root directory of the project, if the file exists
+func readLockFile() error {
+	if project.lockFileRead {
+		return nil
+	}
+	project.lockFileRead = true
+
+	file, err := os.Open(lockFileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := r.ReadString('\n')
+		if (err != nil) && (err != io.EOF) {
+			return err
+		}
+
+		fields := strings.Fields(line)
+		if (len(fields) > 0) && !strings.HasPrefix(fields[0], "#") {
+			if (len(fields) < 3) || (len(fields) > 5) {
+				return errors.New(lockFileName + ":" + strconv.Itoa(lineNumber) + ": invalid entry")
+			}
+
+			entry := &lock_entry_t{
+				kind:           fields[0],
+				repositoryPath: fields[1],
+				commit:         fields[2],
+			}
+			optional := fields[3:]
+			if (len(optional) > 0) && strings.HasPrefix(optional[len(optional)-1], checksumPrefix) {
+				entry.checksum = optional[len(optional)-1][len(checksumPrefix):]
+				optional = optional[0 : len(optional)-1]
+			}
+			switch len(optional) {
+			case 0:
+			case 1:
+				entry.revision = optional[0]
+			default:
+				return errors.New(lockFileName + ":" + strconv.Itoa(lineNumber) + ": invalid entry")
+			}
+
+			if _, duplicate := project.lockEntries[entry.repositoryPath]; duplicate {
+				return errors.New(lockFileName + ":" + strconv.Itoa(lineNumber) + ": duplicate entry for repository \"" + entry.repositoryPath + "\"")
+			}
+			project.lockEntries[entry.repositoryPath] = entry
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if *flag_debug {
+		println("read:", lockFileName)
+	}
+
+	return nil
+}
+
+// Records the revisions of the local copies of all remote packages in the lock file.
+// Remote packages without a local copy, and remote packages whose local copies
+// are not known to be at the requested revisions, keep their previous entries.
+func writeLockFile() error {
+	err := readLockFile()
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, remotePackage := range project.remotePackages {
+		repository := remotePackage.repository
+
+		// The entry of a package replaced by a local directory is kept as it is
+		if remotePackage.isReplaced() {
+			if entry, locked := project.lockEntries[remotePackage.originalRepository_orNil.Path()]; locked {
+				lines = append(lines, entry.line())
+			}
+			continue
+		}
+
+		var commit string
+		if fileExists(repository.ProjectDir()) {
+			commit, err = repository.CurrentRevision()
+			if err != nil {
+				return err
+			}
+		}
+
+		if (len(commit) == 0) || !remotePackage.canRecordLocalCopy(commit) {
+			if entry, locked := project.lockEntries[repository.Path()]; locked {
+				lines = append(lines, entry.line())
+			}
+			continue
+		}
+
+		// The checksum of a locked commit has been verified when installing the package,
+		// the local copy might contain modifications made afterwards
+		var checksum string
+		if (commit == remotePackage.lockedCommit()) && (len(remotePackage.lockedChecksum()) != 0) {
+			checksum = remotePackage.lockedChecksum()
+		} else {
+			checksum, err = remotePackage.sourceChecksum()
+			if err != nil {
+				return err
+			}
+		}
+
+		entry := &lock_entry_t{
+			kind:           repository.KindName(),
+			repositoryPath: repository.Path(),
+			commit:         commit,
+			revision:       remotePackage.revision,
+			checksum:       checksum,
+		}
+		lines = append(lines, entry.line())
+		project.lockEntries[repository.Path()] = entry
+	}
+	sort.Strings(lines)
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by GOAM, do not edit. Run \"goam update-deps\" to update.\n")
+	for _, line := range lines {
+		buf.WriteString(line + "\n")
+	}
+
+	if *flag_debug {
+		println("write:", lockFileName)
+	}
+
+	return ioutil.WriteFile(lockFileName, buf.Bytes(), 0666)
+}
+
+// Returns whether the commit checked out in the local copy of the package can be recorded
+// in the lock file: the local copy has been updated to the requested revision by 'fetch',
+// or it is at the commit in the lock file, or at the commit denoted by the revision
+// requested by the config files. The local copy of a vendored package is not used
+// by the installation, thus it is recorded only after vendoring.
+func (p *remote_package_t) canRecordLocalCopy(commit string) bool {
+	switch {
+	case p.fetched:
+		return true
+	case p.isVendored():
+		return false
+	case len(p.lockedCommit()) != 0:
+		return commit == p.lockedCommit()
+	case len(p.revision) != 0:
+		return p.atRequestedRevision()
+	}
+
+	// A package installed before the lock file was created
+	_, locked := project.lockEntries[p.repository.Path()]
+	return !locked
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io/ioutil"
+	pathutil "path"
+	"strings"
+	"testing"
+)
+
+func TestReadLockFile(t *testing.T) {
+	_, leave := enterTempDir(t)
+	defer leave()
+
+	lines := []string{
+		"git https://example.com/a.git 0123456789abcdef0123456789abcdef01234567",
+		"git https://example.com/b.git 0123456789abcdef0123456789abcdef01234567 v1.2",
+		"hg https://example.com/c 0123456789ab sha256:" + strings.Repeat("ab", 32),
+		"bzr lp:d revid:someone@example.com-20120101000000-abcdef release sha256:" + strings.Repeat("cd", 32),
+	}
+	writeTestFile(t, lockFileName, "# A comment\n\n"+strings.Join(lines, "\n")+"\n")
+
+	err := readLockFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.lockEntries) != len(lines) {
+		t.Fatalf("expected %d entries, got %d", len(lines), len(project.lockEntries))
+	}
+
+	b := project.lockEntries["https://example.com/b.git"]
+	if (b == nil) || (b.kind != "git") || (b.revision != "v1.2") || (len(b.checksum) != 0) {
+		t.Errorf("unexpected entry: %#v", b)
+	}
+	c := project.lockEntries["https://example.com/c"]
+	if (c == nil) || (c.commit != "0123456789ab") || (len(c.revision) != 0) || (c.checksum != strings.Repeat("ab", 32)) {
+		t.Errorf("unexpected entry: %#v", c)
+	}
+
+	for _, line := range lines {
+		entry := project.lockEntries[strings.Fields(line)[1]]
+		if entry == nil {
+			t.Errorf("no entry for the line %q", line)
+		} else if entry.line() != line {
+			t.Errorf("expected the line %q, got %q", line, entry.line())
+		}
+	}
+}
+
+func TestReadLockFileErrors(t *testing.T) {
+	_, leave := enterTempDir(t)
+	defer leave()
+
+	tests := []struct {
+		contents string
+		err      string
+	}{
+		{"git https://example.com/a.git\n", lockFileName + ":1: invalid entry"},
+		{"# comment\ngit https://example.com/a.git 0123 v1 v2\n", lockFileName + ":2: invalid entry"},
+		{"git https://example.com/a.git 0123 v1 v2 sha256:00 x\n", lockFileName + ":1: invalid entry"},
+		{"git https://example.com/a.git 0123\ngit https://example.com/a.git 4567\n", lockFileName + ":2: duplicate entry"},
+	}
+
+	for _, test := range tests {
+		writeTestFile(t, lockFileName, test.contents)
+		project.lockEntries, project.lockFileRead = make(map[string]*lock_entry_t), false
+
+		err := readLockFile()
+		if (err == nil) || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%q: expected the error %q, got %v", test.contents, test.err, err)
+		}
+	}
+}
+
+// The lock file written by "goam install-deps" is read back without changes
+func TestWriteLockFileRoundTrip(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	origin := pathutil.Join(dir, "origin")
+	createTestGitRepository(t, origin)
+	commit := commitTestFile(t, origin, "foo.go", "package foo\n")
+	runTestGit(t, origin, "tag", "v1")
+	commitTestFile(t, origin, "foo.go", "package foo\n\nconst Version = 2\n")
+	url := "file://" + origin
+
+	restartTestProcess(t, dir)
+	p := defineTestRemotePackage(t, url, "v1")
+	err := installTestDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum, err := p.sourceChecksum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadFile(lockFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restartTestProcess(t, dir)
+	p = defineTestRemotePackage(t, url, "v1")
+	err = readLockFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := project.lockEntries[url]
+	if (entry == nil) || (entry.kind != "git") || (entry.commit != commit) || (entry.revision != "v1") || (entry.checksum != checksum) {
+		t.Fatalf("unexpected entry: %#v", entry)
+	}
+	if p.lockedCommit() != commit {
+		t.Errorf("expected the locked commit %s, got %s", commit, p.lockedCommit())
+	}
+
+	err = writeLockFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newContents, err := ioutil.ReadFile(lockFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(newContents) != string(contents) {
+		t.Errorf("the lock file has changed from:\n%s\nto:\n%s", contents, newContents)
+	}
+}
@@ -13,7 +13,10 @@ func usage() {
 	fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] COMMAND\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "Command is one of:\n")
+	fmt.Fprintf(os.Stderr, "    env\n")
+	fmt.Fprintf(os.Stderr, "    check-config\n")
 	fmt.Fprintf(os.Stderr, "    info\n")
+	fmt.Fprintf(os.Stderr, "    generate\n")
 	fmt.Fprintf(os.Stderr, "    make\n")
 	fmt.Fprintf(os.Stderr, "    make-tests\n")
 	fmt.Fprintf(os.Stderr, "    test [PATTERN]\n")
@@ -22,6 +25,10 @@ func usage() {
 	fmt.Fprintf(os.Stderr, "    install\n")
 	fmt.Fprintf(os.Stderr, "    uninstall\n")
 	fmt.Fprintf(os.Stderr, "    install-deps\n")
+	fmt.Fprintf(os.Stderr, "    update-deps [REPOSITORY]\n")
+	fmt.Fprintf(os.Stderr, "    deps status\n")
+	fmt.Fprintf(os.Stderr, "    vendor\n")
+	fmt.Fprintf(os.Stderr, "    remote-packages\n")
 	fmt.Fprintf(os.Stderr, "    gofmt\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "Options:\n")
@@ -41,11 +48,11 @@ func boot(updateTests bool) (*dir_t, error) {
 		return nil, err
 	}
 
-	for len(newObjects) > 0 {
-		objects := newObjects
+	for len(project.newObjects) > 0 {
+		objects := project.newObjects
 
 		// Clean 'newObjects'
-		newObjects = make(map[object_t]byte)
+		project.newObjects = make(map[object_t]byte)
 
 		for object := range objects {
 			// Maybe infer some more objects.
@@ -62,7 +69,7 @@ func boot(updateTests bool) (*dir_t, error) {
 		return nil, err
 	}
 
-	for _, remotePackage := range remotePackages {
+	for _, remotePackage := range project.remotePackages {
 		err = remotePackage.Check()
 		if err != nil {
 			return nil, err
@@ -76,6 +83,12 @@ func boot(updateTests bool) (*dir_t, error) {
 	return rootObject, nil
 }
 
+// Whether missing Go files generated by commands (or by other objects)
+// are generated while inferring objects. This is enabled only when the project
+// is being built, so that commands such as "goam info" and "goam clean"
+// do not run external tools.
+var generateMissingFiles = false
+
 func info([]string) error {
 	rootObject, err := boot( /*updateTests*/ false)
 	if err != nil {
@@ -91,12 +104,29 @@ func info([]string) error {
 	return nil
 }
 
+func generate([]string) error {
+	_, err := boot( /*updateTests*/ false)
+	if err != nil {
+		return err
+	}
+
+	// Like "go generate", succeed silently if there is nothing to do
+	return runGoGenerators()
+}
+
 func _make([]string) error {
+	generateMissingFiles = true
+
 	rootObject, err := boot( /*updateTests*/ false)
 	if err != nil {
 		return err
 	}
 
+	err = checkBuildTools(rootObject, /*install*/ false)
+	if err != nil {
+		return err
+	}
+
 	err = installAllRemotePackages()
 	if err != nil {
 		return err
@@ -111,11 +141,18 @@ func _make([]string) error {
 }
 
 func makeTests([]string) error {
+	generateMissingFiles = true
+
 	rootObject, err := boot( /*updateTests*/ true)
 	if err != nil {
 		return err
 	}
 
+	err = checkBuildTools(rootObject, /*install*/ false)
+	if err != nil {
+		return err
+	}
+
 	err = installAllRemotePackages()
 	if err != nil {
 		return err
@@ -130,11 +167,18 @@ func makeTests([]string) error {
 }
 
 func runTestsAndBenchmarks(testPattern, benchPattern string) error {
+	generateMissingFiles = true
+
 	rootObject, err := boot( /*updateTests*/ true)
 	if err != nil {
 		return err
 	}
 
+	err = checkBuildTools(rootObject, /*install*/ false)
+	if err != nil {
+		return err
+	}
+
 	err = installAllRemotePackages()
 	if err != nil {
 		return err
@@ -200,6 +244,8 @@ func clean([]string) error {
 }
 
 func install([]string) error {
+	generateMissingFiles = true
+
 	rootObject, err := boot( /*updateTests*/ false)
 	if err != nil {
 		return err
@@ -208,16 +254,21 @@ func install([]string) error {
 	if *flag_gcc {
 		return errors.New("there is no support for installation when using gccgo")
 	}
-	if len(installationCommands) == 0 {
+	if len(project.installationCommands) == 0 {
 		return errors.New("nothing to install")
 	}
 
+	err = checkBuildTools(rootObject, /*install*/ true)
+	if err != nil {
+		return err
+	}
+
 	err = installAllRemotePackages()
 	if err != nil {
 		return err
 	}
 
-	for _, cmd := range installationCommands {
+	for _, cmd := range project.installationCommands {
 		err = cmd.Install(rootObject)
 		if err != nil {
 			return err
@@ -233,11 +284,11 @@ func uninstall([]string) error {
 		return err
 	}
 
-	if len(installationCommands) == 0 {
+	if len(project.installationCommands) == 0 {
 		return errors.New("nothing to uninstall")
 	}
 
-	for _, cmd := range installationCommands {
+	for _, cmd := range project.installationCommands {
 		err = cmd.Uninstall(rootObject)
 		if err != nil {
 			return err
@@ -253,11 +304,100 @@ func installDependencies([]string) error {
 		return err
 	}
 
-	if len(remotePackages) == 0 {
+	if len(project.remotePackages) == 0 {
 		return errors.New("there are no remote packages")
 	}
 
-	return installAllRemotePackages()
+	err = installAllRemotePackages()
+	if err != nil {
+		return err
+	}
+
+	err = verifyAllRemotePackages()
+	if err != nil {
+		return err
+	}
+
+	return writeLockFile()
+}
+
+func updateDependencies(args []string) error {
+	_, err := boot( /*updateTests*/ false)
+	if err != nil {
+		return err
+	}
+
+	if len(project.remotePackages) == 0 {
+		return errors.New("there are no remote packages")
+	}
+
+	if len(args) == 0 {
+		err = updateAllRemotePackages()
+	} else {
+		err = updateRemotePackage(args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeLockFile()
+}
+
+func deps(args []string) error {
+	if args[0] != "status" {
+		return errors.New("invalid deps command: " + args[0])
+	}
+
+	_, err := boot( /*updateTests*/ false)
+	if err != nil {
+		return err
+	}
+
+	if len(project.remotePackages) == 0 {
+		return errors.New("there are no remote packages")
+	}
+
+	buf := bufio.NewWriter(os.Stdout)
+	err = writeRemotePackagesStatus(buf)
+	if err != nil {
+		buf.Flush()
+		return err
+	}
+
+	return buf.Flush()
+}
+
+func vendorDependencies([]string) error {
+	_, err := boot( /*updateTests*/ false)
+	if err != nil {
+		return err
+	}
+
+	if len(project.remotePackages) == 0 {
+		return errors.New("there are no remote packages")
+	}
+
+	err = vendorAllRemotePackages()
+	if err != nil {
+		return err
+	}
+
+	return writeLockFile()
+}
+
+func printRemotePackages([]string) error {
+	_, err := readDir()
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(os.Stdout)
+	err = writeRemotePackages(buf)
+	if err != nil {
+		return err
+	}
+
+	return buf.Flush()
 }
 
 func gofmt([]string) error {
@@ -266,6 +406,11 @@ func gofmt([]string) error {
 		return err
 	}
 
+	err = lookupExecutables([]*Executable{gofmt_exe})
+	if err != nil {
+		return err
+	}
+
 	var files []string
 	err = rootObject.GoFmt(&files)
 	if err != nil {
@@ -284,16 +429,23 @@ type function_info_t struct {
 }
 
 var functionTable = map[string]function_info_t{
-	"info":         {info, 0, 0},
-	"make":         {_make, 0, 0},
-	"make-tests":   {makeTests, 0, 0},
-	"test":         {runTests, 0, 1},
-	"benchmark":    {runBenchmarks, 0, 1},
-	"clean":        {clean, 0, 0},
-	"install":      {install, 0, 0},
-	"uninstall":    {uninstall, 0, 0},
-	"install-deps": {installDependencies, 0, 0},
-	"gofmt":        {gofmt, 0, 0},
+	"env":             {env, 0, 0},
+	"check-config":    {checkConfig, 0, 0},
+	"info":            {info, 0, 0},
+	"generate":        {generate, 0, 0},
+	"make":            {_make, 0, 0},
+	"make-tests":      {makeTests, 0, 0},
+	"test":            {runTests, 0, 1},
+	"benchmark":       {runBenchmarks, 0, 1},
+	"clean":           {clean, 0, 0},
+	"install":         {install, 0, 0},
+	"uninstall":       {uninstall, 0, 0},
+	"install-deps":    {installDependencies, 0, 0},
+	"update-deps":     {updateDependencies, 0, 1},
+	"deps":            {deps, 1, 1},
+	"vendor":          {vendorDependencies, 0, 0},
+	"remote-packages": {printRemotePackages, 0, 0},
+	"gofmt":           {gofmt, 0, 0},
 }
 
 var (
@@ -305,12 +457,23 @@ var (
 	flag_gcc       = flag.Bool("gcc", false, "Use gccgo as the compiler and linker")
 	flag_arch      = flag.String("conf-arch", runtime.GOARCH, "The value of GOARCH to use when interpreting GOAM.conf files")
 	flag_os        = flag.String("conf-os", runtime.GOOS, "The value of GOOS to use when interpreting GOAM.conf files")
+	flag_goroot    = flag.String("goroot", "", "The Go root directory (overrides $GOROOT)")
+	flag_gobin     = flag.String("gobin", "", "The directory where to install executables (overrides $GOBIN)")
+	flag_srcroot   = flag.String("srcroot", "", "Zero-config mode: import paths are directory paths relative to this directory")
+	flag_offline   = flag.Bool("offline", false, "Never download remote packages, use only local copies and vendored packages")
+	flag_depsroot  = flag.String("depsroot", "", "The directory where to clone remote packages and install libraries (overrides DepsRoot)")
 )
 
+func init() {
+	flag.Var(flag_options, "D", "Define an option KEY=VALUE readable in GOAM.conf files via 'Option'")
+	flag.Var(flag_replacements, "replace", "Replace the remote package providing IMPORT_PATH by a local directory: IMPORT_PATH=DIR (overrides Replace)")
+}
+
 func main() {
 	flag.Usage = func() { fmt.Fprintln(os.Stderr); usage() }
 	flag.Parse()
 	initArch()
+	exitIfError(initReplacements())
 
 	args := flag.Args()
 	if *flag_version {
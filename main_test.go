@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	pathutil "path"
+	"strings"
+	"testing"
+)
+
+// Creates a temporary directory, makes it the current directory,
+// and resets the state of the project as if GOAM was started in the directory.
+// The returned function restores the previous state.
+func enterTempDir(t *testing.T) (dir string, leave func()) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err = ioutil.TempDir("", "goam-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chdir(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	initArch()
+	savedProject := project
+	savedSrcRoot := *flag_srcroot
+	savedLibInstallRoot, savedRemotePkgInstallRoot := libInstallRoot, remotePkgInstallRoot
+	savedDepsRoot, savedDepsRootFixed := depsRoot, depsRootFixed
+	project = new_project_state()
+
+	leave = func() {
+		project = savedProject
+		*flag_srcroot = savedSrcRoot
+		libInstallRoot, remotePkgInstallRoot = savedLibInstallRoot, savedRemotePkgInstallRoot
+		depsRoot, depsRootFixed = savedDepsRoot, savedDepsRootFixed
+		os.Chdir(cwd)
+		os.RemoveAll(dir)
+	}
+
+	return dir, leave
+}
+
+// Resets the state of the project as if a new GOAM process was started
+// in the current directory 'dir' with the option "-depsroot=deps"
+func restartTestProcess(t *testing.T, dir string) {
+	project = new_project_state()
+
+	err := setDepsRoot(pathutil.Join(dir, "deps"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	depsRootFixed = true
+}
+
+// Creates the file 'path' (and the missing parent directories) with the specified contents
+func writeTestFile(t *testing.T, path, contents string) {
+	err := mkdirAll(pathutil.Dir(path), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(path, []byte(contents), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Creates the executable shell script 'path'
+func writeTestScript(t *testing.T, path, contents string) {
+	writeTestFile(t, path, "#!/bin/sh\n"+contents)
+
+	err := os.Chmod(path, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Prepends directory 'dir' to $PATH.
+// The returned function restores the previous value of $PATH.
+func prependToPath(t *testing.T, dir string) (restore func()) {
+	path := os.Getenv("PATH")
+
+	err := os.Setenv("PATH", dir+":"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func() { os.Setenv("PATH", path) }
+}
+
+// Runs git in directory 'dir' and returns its output
+func runTestGit(t *testing.T, dir string, args ...string) string {
+	argv := append([]string{git_exe.name}, args...)
+	stdout, _, err := git_exe.run(argv, dir, /*in*/ "", /*mergeStdoutAndStderr*/ true)
+	if err != nil {
+		t.Fatalf("%s: %s", err, stdout)
+	}
+
+	return strings.TrimSpace(stdout)
+}
+
+// Creates a git repository in directory 'dir'. The default branch is "main".
+func createTestGitRepository(t *testing.T, dir string) {
+	err := mkdirAll(dir, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runTestGit(t, dir, "init", "-q")
+	runTestGit(t, dir, "symbolic-ref", "HEAD", "refs/heads/main")
+}
+
+// Writes the file in the git repository, commits the change, and returns the commit ID
+func commitTestFile(t *testing.T, repositoryDir, file, contents string) string {
+	writeTestFile(t, pathutil.Join(repositoryDir, file), contents)
+	runTestGit(t, repositoryDir, "add", file)
+	runTestGit(t, repositoryDir, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit", "-q", "-m", "Change "+file)
+
+	return runTestGit(t, repositoryDir, "rev-parse", "HEAD")
+}
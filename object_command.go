@@ -0,0 +1,260 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	pathutil "path"
+	"strings"
+)
+
+// Represents a command defined by 'Command' in a config file,
+// or by a '//goam:generate' directive in a Go file.
+// The command generates its outputs from its inputs.
+type command_t struct {
+	nop_object_t          // The entry of the first output (or the first input if there are no outputs)
+	parent       *dir_t   // The directory containing the config file, the command runs in this directory
+	outputs      []string // Paths relative to the root directory
+	inputs       []string // Paths relative to the root directory
+	argv         []string
+	env          []string     // Additional environment variables, or nil
+	goFiles      []*go_file_t // Go files generated by the command
+	built        bool
+	nowBuilding  bool
+}
+
+// ==========
+// command_t
+// ==========
+
+func new_command(parent *dir_t, outputs, inputs, argv []string) (*command_t, error) {
+	for _, output := range outputs {
+		if other, defined := project.commands_byOutput[output]; defined {
+			return nil, errors.New("file \"" + output + "\" is an output of multiple commands" +
+				" (" + strings.Join(other.argv, " ") + ")")
+		}
+	}
+
+	var entryPath string
+	if len(outputs) > 0 {
+		entryPath = outputs[0]
+	} else {
+		entryPath = inputs[0]
+	}
+
+	c := &command_t{
+		nop_object_t: nop_object_t{new_entry_from_path(pathutil.Base(entryPath), entryPath)},
+		parent:       parent,
+		outputs:      outputs,
+		inputs:       inputs,
+		argv:         argv,
+	}
+
+	for _, output := range outputs {
+		project.commands_byOutput[output] = c
+	}
+	project.commands = append(project.commands, c)
+	parent.commands = append(parent.commands, c)
+
+	project.newObjects[c] = 0
+	return c, nil
+}
+
+// Registers the Go files generated by the command.
+// Missing Go files are added to the file system model,
+// so that they can be used as ordinary sources.
+func (c *command_t) InferObjects(updateTests bool) error {
+	if c.goFiles != nil {
+		return nil
+	}
+
+	root := c.parent.root()
+	for _, output := range c.outputs {
+		if !strings.HasSuffix(output, ".go") || strings.HasSuffix(output, "_test.go") {
+			continue
+		}
+
+		dirPath, name := pathutil.Split(output)
+		dir := root.getOrCreateSubDirs(strings.Split(pathutil.Clean(dirPath), "/"))
+
+		var goFile *go_file_t
+		switch object := dir.getObject_orNil([]string{name}).(type) {
+		case nil:
+			goFile = new_go_file(new_entry_from_path(name, output), dir)
+			dir.add(goFile)
+		case *go_file_t:
+			goFile = object
+		default:
+			return errors.New("command output \"" + output + "\" was expected to be a Go file")
+		}
+
+		if goFile.generator_orNil != nil {
+			return errors.New("file \"" + output + "\" is generated by multiple objects")
+		}
+		goFile.generator_orNil = c
+
+		c.goFiles = append(c.goFiles, goFile)
+	}
+
+	return nil
+}
+
+func (c *command_t) PrintDependencies(w io.Writer) {
+	fmt.Fprintf(w, "%v <-- %v (%s)\n", c.outputs, c.inputs, strings.Join(c.argv, " "))
+}
+
+func (c *command_t) Info(info *info_t) {
+	if len(c.outputs) > 0 {
+		info.commands[c] = 0
+	}
+}
+
+// Returns whether some of the outputs are missing or older than some of the inputs.
+// A command without outputs is never out of date.
+func (c *command_t) outOfDate() (bool, error) {
+	if len(c.outputs) == 0 {
+		return false, nil
+	}
+
+	var oldestOutput int64 = -1
+	for _, output := range c.outputs {
+		fileInfo, err := os.Stat(output)
+		if err != nil {
+			return true, nil
+		}
+
+		mtime := fileInfo.ModTime().UnixNano()
+		if (oldestOutput == -1) || (mtime < oldestOutput) {
+			oldestOutput = mtime
+		}
+	}
+
+	for _, input := range c.inputs {
+		fileInfo, err := os.Stat(input)
+		if err != nil {
+			return false, errors.New("unable to generate " + strings.Join(c.outputs, " ") + ": missing input \"" + input + "\"")
+		}
+
+		if fileInfo.ModTime().UnixNano() > oldestOutput {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (c *command_t) Make() error {
+	if c.built {
+		return nil
+	}
+
+	if c.nowBuilding {
+		return errors.New("circular dependency involving \"" + c.path + "\"")
+	}
+	c.nowBuilding = true
+	defer func() { c.nowBuilding = false }()
+
+	// Inputs can be outputs of other commands
+	for _, input := range c.inputs {
+		if other, isOutput := project.commands_byOutput[input]; isOutput {
+			err := other.Make()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	rebuild, err := c.outOfDate()
+	if err != nil {
+		return err
+	}
+
+	if rebuild {
+		err = c.run()
+		if err != nil {
+			return err
+		}
+	}
+
+	c.built = true
+	return nil
+}
+
+// Returns the name of the executable run by the command.
+// A relative path (such as "./gen.sh" or "tools/gen") is relative to the directory
+// of the config file, other names are searched in $PATH. The command runs in the directory
+// of the config file, thus a relative path is made absolute instead of relative to the root.
+func (c *command_t) executableName() (string, error) {
+	name := c.argv[0]
+	if !strings.Contains(name, "/") || pathutil.IsAbs(name) {
+		return name, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	return pathutil.Join(cwd, c.parent.path, name), nil
+}
+
+// Runs the command, regardless of whether the outputs are up to date
+func (c *command_t) run() error {
+	for _, output := range c.outputs {
+		err := mkdirAll(pathutil.Dir(output), 0777)
+		if err != nil {
+			return err
+		}
+	}
+
+	name, err := c.executableName()
+	if err != nil {
+		return err
+	}
+
+	exe := &Executable{name: name}
+	if c.env != nil {
+		err = exe.runWithEnv(c.argv, /*dir*/ c.parent.path, c.env)
+	} else {
+		err = exe.runSimply(c.argv, /*dir*/ c.parent.path, /*dontPrint*/ false)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, output := range c.outputs {
+		if !fileExists(output) {
+			return errors.New("command \"" + strings.Join(c.argv, " ") + "\" failed to generate \"" + output + "\"")
+		}
+	}
+
+	c.UpdateFileInfo()
+	for _, goFile := range c.goFiles {
+		goFile.UpdateFileInfo()
+	}
+
+	return nil
+}
+
+// Removes the outputs
+func (c *command_t) Clean() error {
+	for _, output := range c.outputs {
+		if fileExists(output) {
+			if *flag_debug {
+				println("remove:", output)
+			}
+			err := os.Remove(output)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	c.UpdateFileInfo()
+	for _, goFile := range c.goFiles {
+		goFile.UpdateFileInfo()
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+// A relative executable is relative to the directory of the config file defining the command
+func TestCommandInSubdirectoryRunsRelativeScript(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	writeTestScript(t, "sub/gen.sh", "cat in.txt > out.txt\n")
+	writeTestFile(t, "sub/in.txt", "generated\n")
+
+	*flag_srcroot = dir
+	root, err := boot( /*updateTests*/ false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := root.getOrCreateSubDirs([]string{"sub"})
+	c, err := new_command(sub, /*outputs*/ []string{"sub/out.txt"}, /*inputs*/ []string{"sub/in.txt"}, []string{"./gen.sh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Make()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ioutil.ReadFile("sub/out.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "generated\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
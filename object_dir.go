@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	pathutil "path"
+	"strings"
 )
 
 // Represents a directory
@@ -15,6 +16,13 @@ type dir_t struct {
 	makefile_orNil *makefile_t
 	numTestFiles   uint
 	objects        []object_t
+	commands       []*command_t // Commands defined by the config file in this directory
+
+	// Objects created by file handlers which have directory inference rules
+	handledObjects map[*file_handler_t][]object_t
+
+	// The name of the package of Go files in the directory (only used in zero-config mode)
+	packageName_orEmpty string
 }
 
 func new_dir(entry entry_t, parent_orNil *dir_t) *dir_t {
@@ -25,8 +33,11 @@ func new_dir(entry entry_t, parent_orNil *dir_t) *dir_t {
 		makefile_orNil: nil,
 		numTestFiles:   0,
 		objects:        nil,
+		commands:       nil,
+
+		packageName_orEmpty: "",
 	}
-	newObjects[d] = 0
+	project.newObjects[d] = 0
 	return d
 }
 
@@ -38,6 +49,124 @@ func (d *dir_t) root() *dir_t {
 	return d
 }
 
+// Returns the settings in effect in directory 'd'.
+// The settings are taken from the config file in the nearest directory
+// (starting from 'd' and going up) which has a config file.
+func (d *dir_t) settings() *config_settings_t {
+	for dir := d; dir != nil; dir = dir.parent_orNil {
+		if (dir.config_orNil != nil) && (dir.config_orNil.settings != nil) {
+			return dir.config_orNil.settings
+		}
+	}
+
+	return project.defaultSettings
+}
+
+// Returns the import path derived from the position of directory 'd'
+// relative to the directory which declared the import prefix
+func (d *dir_t) autoImportPath() (string, error) {
+	settings := d.settings()
+	if !settings.haveImportPrefix() {
+		return "", errors.New("directory \"" + d.path + "\": unable to derive the import path, no import prefix has been defined")
+	}
+
+	var relPath string
+	switch {
+	case d.path == settings.importPrefixDir:
+		relPath = ""
+	case settings.importPrefixDir == ".":
+		relPath = d.path
+	case strings.HasPrefix(d.path, settings.importPrefixDir+"/"):
+		relPath = d.path[len(settings.importPrefixDir)+1:]
+	default:
+		panic("directory \"" + d.path + "\" is not under \"" + settings.importPrefixDir + "\"")
+	}
+
+	var importPath string
+	switch {
+	case len(relPath) == 0:
+		importPath = settings.importPrefix
+	case len(settings.importPrefix) == 0:
+		importPath = relPath
+	default:
+		importPath = settings.importPrefix + "/" + relPath
+	}
+
+	if len(importPath) == 0 {
+		return "", errors.New("directory \"" + d.path + "\" is the root of the source tree," +
+			" it cannot contain a package other than \"main\"")
+	}
+
+	return importPath, nil
+}
+
+// Returns the import path of the package built from the Go files in directory 'd'.
+// The import path is either specified by the config file,
+// or it is derived from the inherited import prefix.
+func (d *dir_t) targetPackage() (string, error) {
+	config := d.config_orNil
+	if (config != nil) && (len(config.targetPackage_orEmpty) != 0) {
+		return config.targetPackage_orEmpty, nil
+	}
+
+	if d.settings().haveImportPrefix() {
+		importPath, err := d.autoImportPath()
+		if err != nil {
+			return "", err
+		}
+
+		project.inferredPackages[d.path] = importPath
+		return importPath, nil
+	}
+
+	if config == nil {
+		return "", errors.New("directory \"" + d.path + "\" requires a " + configFileName + " file" +
+			" with a specification of the target package or executable")
+	}
+
+	return "", errors.New("config file for directory \"" + d.path + "\" does not specify the target package")
+}
+
+// Records the name of a package whose Go files are in directory 'd'.
+// Fails if Go files of a different package have been recorded before.
+func (d *dir_t) checkPackageName(packageName string) error {
+	if len(d.packageName_orEmpty) == 0 {
+		d.packageName_orEmpty = packageName
+	} else if d.packageName_orEmpty != packageName {
+		return errors.New("directory \"" + d.path + "\" is ambiguous: it contains Go files of packages" +
+			" \"" + d.packageName_orEmpty + "\" and \"" + packageName + "\"")
+	}
+
+	return nil
+}
+
+// Returns the name of the directory.
+// In case of the root directory, the name is determined from the current working directory.
+func (d *dir_t) baseName() (string, error) {
+	if d.name != "." {
+		return d.name, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	return pathutil.Base(cwd), nil
+}
+
+// Returns whether the name of sub-directory 'subdir' matches
+// any of the patterns declared by 'IgnoreDirNames'
+func (d *dir_t) ignoresDirName(subdir *dir_t) bool {
+	for _, pattern := range d.settings().ignoredDirNames {
+		if matched, _ := pathutil.Match(pattern, subdir.name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (d *dir_t) getObject_orNil(path []string) object_t {
 	switch {
 	case len(path) == 0:
@@ -95,6 +224,23 @@ func (d *dir_t) getOrCreateSubDirs(names []string) *dir_t {
 	return d
 }
 
+// Returns whether 'd' or any of its sub-directories contains a Makefile
+func (d *dir_t) containsMakefiles() bool {
+	if d.makefile_orNil != nil {
+		return true
+	}
+
+	for _, object := range d.objects {
+		if subdir, isDir := object.(*dir_t); isDir {
+			if subdir.containsMakefiles() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func (d *dir_t) mkdir_ifDoesNotExist() error {
 	if d.parent_orNil != nil {
 		err := d.parent_orNil.mkdir_ifDoesNotExist()
@@ -157,6 +303,40 @@ func (d *dir_t) getOrCreate_goTestMain(fileName, importPath string, testDir *dir
 	return t, nil
 }
 
+func (d *dir_t) getOrCreate_goEmbed(spec *embed_spec_t, pkgDir *dir_t, packageName string) (*go_embed_t, error) {
+	var e *go_embed_t
+
+	fileName := embedFileName(spec.variable)
+	path := pathutil.Join(d.path, fileName)
+
+	var _e object_t = d.getObject_orNil([]string{fileName})
+	if _e == nil {
+		// Create a new instance of 'go_embed_t'
+		e = new_go_embed(new_nonexistent_entry(fileName, path), /*parent*/ d, pkgDir, spec, packageName)
+		d.add(e)
+	} else {
+		var isEmbed bool
+		e, isEmbed = _e.(*go_embed_t)
+		if !isEmbed {
+			if goFile, isGoFile := _e.(*go_file_t); isGoFile {
+				// Transform the object's type: go_file_t --> go_embed_t
+				d.removeObject(goFile)
+				e = new_go_embed(goFile.entry_t, /*parent*/ d, pkgDir, spec, packageName)
+				d.add(e)
+			} else {
+				return nil, errors.New("file \"" + _e.Path() + "\" has an invalid type")
+			}
+		}
+	}
+
+	err := e.setPackageName(packageName)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
 func (d *dir_t) getOrCreate_compilationUnit(name string) (*compilation_unit_t, error) {
 	var compilationUnit *compilation_unit_t
 
@@ -219,8 +399,8 @@ func (d *dir_t) getOrCreate_executable(name string) (*executable_t, error) {
 
 func (d *dir_t) removeObject(o object_t) {
 	// Optional: remove from 'newObjects'
-	if _, contains := newObjects[o]; contains {
-		delete(newObjects, o)
+	if _, contains := project.newObjects[o]; contains {
+		delete(project.newObjects, o)
 	}
 
 	// Remove from 'd.objects'
@@ -240,13 +420,19 @@ func (d *dir_t) UpdateFileSystemModel() {
 	for _, object := range d.objects {
 		object.UpdateFileSystemModel()
 	}
+	for _, command := range d.commands {
+		command.UpdateFileSystemModel()
+	}
 }
 
 func (d *dir_t) InferObjects(updateTests bool) error {
-	return nil
+	return d.inferObjectsFromHandlers(updateTests)
 }
 
 func (d *dir_t) PrintDependencies(w io.Writer) {
+	for _, command := range d.commands {
+		command.PrintDependencies(w)
+	}
 	for _, object := range d.objects {
 		object.PrintDependencies(w)
 	}
@@ -269,6 +455,9 @@ func (d *dir_t) shouldRemove() bool {
 }
 
 func (d *dir_t) Info(info *info_t) {
+	for _, command := range d.commands {
+		command.Info(info)
+	}
 	for _, object := range d.objects {
 		object.Info(info)
 	}
@@ -286,6 +475,14 @@ func (d *dir_t) Make() error {
 		return err
 	}
 
+	// Run commands before building anything, their outputs might be used by a Makefile
+	for _, command := range d.commands {
+		err = command.Make()
+		if err != nil {
+			return err
+		}
+	}
+
 	haveMakefile := (d.makefile_orNil != nil)
 	if haveMakefile {
 		// Execute "make"
@@ -362,6 +559,14 @@ func (d *dir_t) Clean() error {
 				return err
 			}
 		}
+
+		// Remove files generated by commands
+		for _, command := range d.commands {
+			err = command.Clean()
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	if d.exists {
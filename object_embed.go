@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	pathutil "path"
+	"sort"
+	"strconv"
+)
+
+// Files to embed into a package, defined by 'EmbedFiles' in a config file
+type embed_spec_t struct {
+	variable string
+	patterns []string // Relative to the directory of the config file
+}
+
+// Represents a generated Go file (such as "_obj/_embed_VAR.go") which defines
+// a variable mapping the paths of embedded files to their contents
+type go_embed_t struct {
+	nop_object_t
+	parent      *dir_t
+	pkgDir      *dir_t // The directory of the package embedding the files
+	spec        *embed_spec_t
+	packageName string
+	contents    *go_file_contents_t // Initially nil
+	refresh     bool
+}
+
+// Returns the name of the Go file generated for the variable
+func embedFileName(variable string) string {
+	return "_embed_" + variable + ".go"
+}
+
+// ==========
+// go_embed_t
+// ==========
+
+func new_go_embed(entry entry_t, parent *dir_t, pkgDir *dir_t, spec *embed_spec_t, packageName string) *go_embed_t {
+	e := &go_embed_t{
+		nop_object_t: nop_object_t{entry},
+		parent:       parent,
+		pkgDir:       pkgDir,
+		spec:         spec,
+		packageName:  packageName,
+		refresh:      true,
+	}
+	project.newObjects[e] = 0
+	return e
+}
+
+func (e *go_embed_t) setPackageName(packageName string) error {
+	if e.packageName != packageName {
+		return errors.New("failed to generate \"" + e.path + "\": EmbedFiles cannot be used" +
+			" in a directory containing multiple packages")
+	}
+
+	return nil
+}
+
+// Generates the source code, and updates the file if the source code
+// differs from the current one. Thus, the package is recompiled
+// only if some of the embedded files have changed.
+func (e *go_embed_t) refreshIfNeeded() error {
+	if !e.refresh {
+		return nil
+	}
+
+	files, err := e.pkgDir.expandFilePatterns(e.spec.patterns)
+	if err != nil {
+		return errors.New("EmbedFiles(\"" + e.spec.variable + "\"): " + err.Error())
+	}
+	sort.Strings(files)
+
+	// Get the current source code
+	var oldContents []byte
+	if e.exists {
+		oldContents, err = ioutil.ReadFile(e.path)
+		if err != nil {
+			return err
+		}
+	} else {
+		oldContents = nil
+	}
+
+	// Create the new source code
+	var buf *bytes.Buffer
+	{
+		buf = bytes.NewBuffer(make([]byte, 0, 1000))
+		buf.WriteString("// Generated by GOAM, do not edit\n")
+		buf.WriteString("\n")
+		buf.WriteString("package " + e.packageName + "\n")
+		buf.WriteString("\n")
+		buf.WriteString("var " + e.spec.variable + " = map[string][]byte{\n")
+		for _, file := range files {
+			data, err := ioutil.ReadFile(pathutil.Join(e.pkgDir.path, file))
+			if err != nil {
+				return err
+			}
+
+			buf.WriteString("\t" + strconv.Quote(file) + ": []byte(" + strconv.Quote(string(data)) + "),\n")
+		}
+		buf.WriteString("}\n")
+	}
+
+	// Update the file if the new source code differs from the current one
+	if (oldContents == nil) || (bytes.Equal(oldContents, buf.Bytes()) == false) {
+		if *flag_debug {
+			println("refresh:", e.path)
+		}
+		err = e.parent.mkdir_ifDoesNotExist()
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(e.path, buf.Bytes(), 0666)
+		if err != nil {
+			return err
+		}
+
+		e.UpdateFileInfo()
+	}
+
+	e.refresh = false
+	return nil
+}
+
+func (e *go_embed_t) Parent() *dir_t {
+	return e.parent
+}
+
+func (e *go_embed_t) Contents() (*go_file_contents_t, error) {
+	if e.contents == nil {
+		// The generated source code does not import any packages
+		e.contents = &go_file_contents_t{
+			packageName: e.packageName,
+		}
+	}
+
+	return e.contents, nil
+}
+
+func (e *go_embed_t) Make() error {
+	return e.refreshIfNeeded()
+}
+
+func (e *go_embed_t) Clean() error {
+	var err error
+	if e.exists {
+		if *flag_debug {
+			println("remove:", e.path)
+		}
+		err = os.Remove(e.path)
+		if err == nil {
+			e.exists = false
+		}
+	} else {
+		err = nil
+	}
+
+	return err
+}
@@ -24,8 +24,9 @@ type go_source_code_t interface {
 // Represents a FILE.go
 type go_file_t struct {
 	entry_t
-	parent   *dir_t
-	contents *go_file_contents_t // Initially nil
+	parent          *dir_t
+	contents        *go_file_contents_t // Initially nil
+	generator_orNil object_t            // The object generating the file, such as a command
 }
 
 // Represents a FILE_test.go
@@ -52,6 +53,9 @@ type go_file_contents_t struct {
 	importedPackages []string
 	tests            []string
 	benchmarks       []string
+	buildConstraints []string // The arguments of "// +build" lines
+
+	generateDirectives []*go_generate_directive_t // The '//goam:generate' comments
 }
 
 // =========
@@ -63,7 +67,7 @@ func new_go_file(entry entry_t, parent *dir_t) *go_file_t {
 		entry_t: entry,
 		parent:  parent,
 	}
-	newObjects[f] = 0
+	project.newObjects[f] = 0
 	return f
 }
 
@@ -71,7 +75,25 @@ func (f *go_file_t) Parent() *dir_t {
 	return f.parent
 }
 
+// Returns whether the file is generated by an object (such as a command)
+// which cannot be run yet because the project is not being built
+func (f *go_file_t) notGeneratedYet() bool {
+	return (f.generator_orNil != nil) && !f.exists && !generateMissingFiles
+}
+
 func (f *go_file_t) Contents() (*go_file_contents_t, error) {
+	if (f.generator_orNil != nil) && !f.exists {
+		if !generateMissingFiles {
+			return nil, errors.New("file \"" + f.path + "\" has not been generated yet")
+		}
+
+		// The file has to be generated before it can be parsed
+		err := f.Make()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if f.contents == nil {
 		var err error
 		f.contents, err = parse_go_file_contents(f.path, /*test*/ false)
@@ -92,22 +114,59 @@ func inferObjects(f go_source_code_t, test bool) error {
 		return nil
 	}
 
+	// Go files in temporary directories are generated by GOAM
+	if f.Parent().shouldRemove() {
+		return nil
+	}
+
 	{
+		// Files generated into a temporary directory (such as "_obj/FILE.pb.go")
+		// belong to the package even if they are not listed in 'PackageFiles'
+		generated := (pathutil.Dir(f.Path()) != pathutil.Clean(f.Parent().Path()))
+
 		config := f.Parent().config_orNil
-		if (config != nil) && config.ignoresGoFile(f) {
+		if (config != nil) && !generated && config.ignoresGoFile(f) {
 			return nil
 		}
 	}
 
+	if goFile, isGoFile := f.(*go_file_t); isGoFile && goFile.notGeneratedYet() {
+		if *flag_debug {
+			println("ignore (not generated yet):", f.Path())
+		}
+		return nil
+	}
+
 	contents, err := f.Contents()
 	if err != nil {
 		return err
 	}
 
+	if !contents.satisfiesBuildConstraints(f.Parent().settings().buildTags) {
+		if *flag_debug {
+			println("ignore (build constraints):", f.Path())
+		}
+		return nil
+	}
+
 	if test && (contents.packageName == "main") {
 		return errors.New("cannot perform tests if the package is \"main\"")
 	}
 
+	if goFile, isGoFile := f.(*go_file_t); isGoFile && !test {
+		err = goFile.inferGenerators(contents)
+		if err != nil {
+			return err
+		}
+	}
+
+	if project.zeroConfig {
+		err = f.Parent().checkPackageName(contents.packageName)
+		if err != nil {
+			return err
+		}
+	}
+
 	// If there is no Makefile:
 	//   If not in test mode:
 	//    - expect a file like "_obj/PACKAGE.8"
@@ -137,7 +196,7 @@ func inferObjects(f go_source_code_t, test bool) error {
 			if contents.packageName != "main" {
 				compilationUnit_name = contents.packageName + o_ext
 			} else {
-				if pathFromMapping, haveMapping := source2executable[f.Path()]; haveMapping {
+				if pathFromMapping, haveMapping := project.source2executable[f.Path()]; haveMapping {
 					compilationUnit_name = pathutil.Base(pathFromMapping) + o_ext
 				} else {
 					compilationUnit_name = contents.packageName + o_ext
@@ -151,6 +210,19 @@ func inferObjects(f go_source_code_t, test bool) error {
 
 			// Register 'f' with the 'compilationUnit'
 			compilationUnit.addSourceCode(f)
+
+			// Register files generated by 'EmbedFiles' with the 'compilationUnit'
+			if parent.config_orNil != nil {
+				for _, spec := range parent.config_orNil.embeds {
+					var embed *go_embed_t
+					embed, err = objDir.getOrCreate_goEmbed(spec, parent, contents.packageName)
+					if err != nil {
+						return err
+					}
+
+					compilationUnit.addSourceCode(embed)
+				}
+			}
 		}
 
 		if contents.packageName != "main" {
@@ -161,17 +233,9 @@ func inferObjects(f go_source_code_t, test bool) error {
 			//  - expect file "_test/main.go"
 
 			var target string
-			{
-				config := f.Parent().config_orNil
-				if config == nil {
-					return errors.New("directory \"" + f.Parent().path + "\" requires a " + configFileName + " file" +
-						" with a specification of the target package or executable")
-				}
-
-				target = config.targetPackage_orEmpty
-				if len(target) == 0 {
-					return errors.New("config file for directory \"" + f.Parent().path + "\" does not specify the target package")
-				}
+			target, err = f.Parent().targetPackage()
+			if err != nil {
+				return err
 			}
 
 			dirPath, baseName := pathutil.Split(target)
@@ -212,10 +276,17 @@ func inferObjects(f go_source_code_t, test bool) error {
 			var exe_name string
 			var exe_dir *dir_t
 
-			if pathFromMapping, haveMapping := source2executable[f.Path()]; haveMapping {
+			if pathFromMapping, haveMapping := project.source2executable[f.Path()]; haveMapping {
 				dir, file := pathutil.Split(pathFromMapping)
 				exe_name = file
 				exe_dir = parent.root().getOrCreateSubDirs(strings.Split(dir, "/"))
+			} else if project.zeroConfig {
+				exe_name, err = parent.baseName()
+				if err != nil {
+					return err
+				}
+				exe_dir = parent
+				project.inferredExecutables[pathutil.Join(exe_dir.path, exe_name)] = parent.path
 			} else {
 				exe_name = defaultExeName
 				exe_dir = parent
@@ -260,6 +331,25 @@ func (f *go_file_t) Info(info *info_t) {
 }
 
 func (f *go_file_t) Make() error {
+	if f.generator_orNil != nil {
+		mtime := f.mtime
+
+		err := f.generator_orNil.Make()
+		if err != nil {
+			return err
+		}
+
+		f.UpdateFileInfo()
+		if !f.exists {
+			return errors.New("failed to generate \"" + f.path + "\"")
+		}
+
+		if f.mtime != mtime {
+			// The file has been regenerated, its imports might have changed
+			f.contents = nil
+		}
+	}
+
 	return nil
 }
 
@@ -272,6 +362,11 @@ func (f *go_file_t) RunTests(testPattern, benchPattern string, errors *[]error)
 }
 
 func (f *go_file_t) Clean() error {
+	if f.generator_orNil != nil {
+		// The generator knows which files to remove
+		return f.generator_orNil.Clean()
+	}
+
 	remove := false
 	if f.name == "_testmain.go" {
 		remove = true
@@ -296,13 +391,20 @@ func (f *go_file_t) Clean() error {
 }
 
 func (f *go_file_t) GoFmt(files *[]string) error {
-	if _, disabled := disabledGoFmt[pathutil.Clean(f.path)]; disabled {
+	if _, disabled := project.disabledGoFmt[pathutil.Clean(f.path)]; disabled {
 		if *flag_debug {
 			println("disabled gofmt:", f.path)
 		}
 		return nil
 	}
 
+	if f.generator_orNil != nil {
+		if *flag_debug {
+			println("no gofmt (generated):", f.path)
+		}
+		return nil
+	}
+
 	if strings.HasPrefix(f.name, "_cgo_") || strings.HasSuffix(f.name, ".cgo1.go") {
 		if *flag_debug {
 			println("no gofmt (cgo):", f.path)
@@ -325,7 +427,7 @@ func new_go_test(entry entry_t, parent *dir_t) *go_test_t {
 		entry_t: entry,
 		parent:  parent,
 	}
-	newObjects[t] = 0
+	project.newObjects[t] = 0
 	return t
 }
 
@@ -378,7 +480,7 @@ func (t *go_test_t) Clean() error {
 }
 
 func (t *go_test_t) GoFmt(files *[]string) error {
-	if _, disabled := disabledGoFmt[pathutil.Clean(t.path)]; disabled {
+	if _, disabled := project.disabledGoFmt[pathutil.Clean(t.path)]; disabled {
 		if *flag_debug {
 			println("disabled gofmt:", t.path)
 		}
@@ -401,7 +503,7 @@ func new_go_testMain(entry entry_t, parent *dir_t, importPath string) *go_testMa
 		importPath: importPath,
 		refresh:    true,
 	}
-	newObjects[t] = 0
+	project.newObjects[t] = 0
 	return t
 }
 
@@ -650,13 +752,23 @@ func parse_go_file_contents(filePath string, test bool) (*go_file_contents_t, er
 		println("parse:", filePath)
 	}
 
-	var mode uint = 0
+	var mode uint = parser.ParseComments
 	if !test {
-		mode = parser.ImportsOnly
+		mode |= parser.ImportsOnly
+	}
+
+	src, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
 	}
 
 	var file *ast.File
-	file, err := parser.ParseFile(token.NewFileSet(), filePath, /*src*/ nil, mode)
+	file, err = parser.ParseFile(token.NewFileSet(), filePath, src, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	generateDirectives, err := parseGenerateDirectives(filePath, src)
 	if err != nil {
 		return nil, err
 	}
@@ -684,11 +796,30 @@ func parse_go_file_contents(filePath string, test bool) (*go_file_contents_t, er
 		}
 	}
 
+	// Extract build constraints.
+	// Only comments preceding the package clause can contain build constraints.
+	var buildConstraints []string
+	for _, commentGroup := range file.Comments {
+		if commentGroup.Pos() >= file.Package {
+			break
+		}
+
+		for _, comment := range commentGroup.List {
+			line := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+			if strings.HasPrefix(line, "+build ") {
+				buildConstraints = append(buildConstraints, strings.TrimSpace(line[len("+build "):]))
+			}
+		}
+	}
+
 	contents := &go_file_contents_t{
 		packageName:      file.Name.Name,
 		importedPackages: importedPackages,
 		tests:            v.tests,
 		benchmarks:       v.benchmarks,
+		buildConstraints: buildConstraints,
+
+		generateDirectives: generateDirectives,
 	}
 
 	if *flag_debug {
@@ -729,3 +860,55 @@ func (f *go_file_contents_t) makePrerequisites(testImportPath_orEmpty string) ([
 
 	return pkgs, nil
 }
+
+// Returns whether the file can be compiled
+// if the build tags are 'tags' (plus the values of -conf-os, -conf-arch and the compiler kind).
+// Lines are ANDed, space-separated options are ORed,
+// comma-separated terms are ANDed, and "!" negates a term.
+func (f *go_file_contents_t) satisfiesBuildConstraints(tags []string) bool {
+	if len(f.buildConstraints) == 0 {
+		return true
+	}
+
+	tagSet := make(map[string]byte)
+	tagSet[*flag_os] = 0
+	tagSet[*flag_arch] = 0
+	if *flag_gcc {
+		tagSet["gccgo"] = 0
+	} else {
+		tagSet["gc"] = 0
+	}
+	for _, tag := range tags {
+		tagSet[tag] = 0
+	}
+
+	for _, line := range f.buildConstraints {
+		lineSatisfied := false
+		for _, option := range strings.Fields(line) {
+			optionSatisfied := true
+			for _, term := range strings.Split(option, ",") {
+				negated := strings.HasPrefix(term, "!")
+				if negated {
+					term = term[1:]
+				}
+
+				_, isSet := tagSet[term]
+				if isSet == negated {
+					optionSatisfied = false
+					break
+				}
+			}
+
+			if optionSatisfied {
+				lineSatisfied = true
+				break
+			}
+		}
+
+		if !lineSatisfied {
+			return false
+		}
+	}
+
+	return true
+}
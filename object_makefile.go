@@ -49,7 +49,7 @@ func new_makefile(entry entry_t, parent *dir_t) (*makefile_t, error) {
 		return nil, errors.New("directory \"" + parent.path + "\" contains multiple makefiles")
 	}
 
-	newObjects[makefile] = 0
+	project.newObjects[makefile] = 0
 	return makefile, nil
 }
 
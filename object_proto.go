@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	pathutil "path"
+	"strings"
+)
+
+// Represents a FILE.proto.
+// The protocol buffer definitions are translated by protoc into "_obj/FILE.pb.go".
+type proto_file_t struct {
+	nop_object_t
+	parent      *dir_t
+	goFile      *go_file_t // The generated Go file, initially nil
+	built       bool
+	nowBuilding bool
+}
+
+var protoc_exe = &Executable{
+	name: "protoc",
+}
+
+var proto_fileHandler = &file_handler_t{
+	name:           "proto",
+	description:    "protocol buffers",
+	phase:          FILE_HANDLER_AFTER_CONFIG,
+	optional:       true,
+	enabledDefault: true,
+	match: func(fileName string, fi os.FileInfo) bool {
+		return strings.HasSuffix(fileName, ".proto")
+	},
+	create: func(entry entry_t, parent *dir_t) (object_t, error) {
+		return new_proto_file(entry, parent), nil
+	},
+	inferDir_orNil: inferProtoFiles,
+}
+
+// Selects the protocol buffer files to translate in directory 'dir'.
+// If the config file in the directory declares 'Protos', only the declared files are translated.
+func inferProtoFiles(dir *dir_t, objects []object_t, updateTests bool) error {
+	var selected map[string]byte = nil
+	if (dir.config_orNil != nil) && (dir.config_orNil.protoPatterns != nil) {
+		files, err := dir.expandFilePatterns(dir.config_orNil.protoPatterns)
+		if err != nil {
+			return errors.New(dir.config_orNil.Path() + ": Protos: " + err.Error())
+		}
+
+		selected = make(map[string]byte)
+		for _, file := range files {
+			if !strings.HasSuffix(file, ".proto") {
+				return errors.New(dir.config_orNil.Path() + ": Protos: file \"" + file + "\" does not end with \".proto\"")
+			}
+			selected[file] = 0
+		}
+	}
+
+	for _, object := range objects {
+		p := object.(*proto_file_t)
+		if selected != nil {
+			if _, isSelected := selected[p.name]; !isSelected {
+				continue
+			}
+		}
+
+		p.expectGoFile()
+	}
+
+	return nil
+}
+
+// ============
+// proto_file_t
+// ============
+
+func new_proto_file(entry entry_t, parent *dir_t) *proto_file_t {
+	p := &proto_file_t{
+		nop_object_t: nop_object_t{entry},
+		parent:       parent,
+	}
+	project.protoFiles = append(project.protoFiles, p)
+	project.newObjects[p] = 0
+	return p
+}
+
+// Expects the file "_obj/FILE.pb.go" generated from FILE.proto.
+// The generated Go file belongs to the directory of FILE.proto,
+// thus it is compiled into the package defined by the directory.
+func (p *proto_file_t) expectGoFile() {
+	if p.goFile != nil {
+		return
+	}
+
+	objDir := p.parent.getOrCreateSubDir("_obj")
+	name := p.NameWithoutExtension() + ".pb.go"
+	path := pathutil.Join(objDir.path, name)
+
+	p.goFile = new_go_file(new_entry_from_path(name, path), /*parent*/ p.parent)
+	p.goFile.generator_orNil = p
+
+	// Replace the file found when reading "_obj" (if any).
+	// The file is listed in "_obj", so that it is removed before "_obj" is removed.
+	if existing := objDir.getObject_orNil([]string{name}); existing != nil {
+		objDir.removeObject(existing)
+	}
+	objDir.add(p.goFile)
+}
+
+func (p *proto_file_t) PrintDependencies(w io.Writer) {
+	if p.goFile != nil {
+		fmt.Fprintf(w, "%s <-- [%s]\n", p.goFile.path, p.path)
+	}
+}
+
+// Returns the paths of the files imported by 'path', directly or indirectly.
+// Imports are resolved relative to 'includeDir'. Files which do not exist
+// (such as files provided by the protoc installation) are skipped.
+func protoImports(path, includeDir string, visited map[string]byte) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var imports []string
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadString('\n')
+		if (err != nil) && (err != io.EOF) {
+			return nil, err
+		}
+
+		// Recognize lines like: import "other.proto";
+		fields := strings.Fields(line)
+		if (len(fields) >= 2) && (fields[0] == "import") {
+			arg := fields[len(fields)-1]
+			if strings.HasSuffix(arg, ";") {
+				arg = arg[0 : len(arg)-1]
+			}
+			if (len(arg) > 2) && (arg[0] == '"') && (arg[len(arg)-1] == '"') {
+				importPath := pathutil.Join(includeDir, arg[1:len(arg)-1])
+				if _, alreadyVisited := visited[importPath]; !alreadyVisited && fileExists(importPath) {
+					visited[importPath] = 0
+					imports = append(imports, importPath)
+
+					indirect, err := protoImports(importPath, includeDir, visited)
+					if err != nil {
+						return nil, err
+					}
+					imports = append(imports, indirect...)
+				}
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return imports, nil
+}
+
+func (p *proto_file_t) Make() error {
+	if p.built || (p.goFile == nil) {
+		return nil
+	}
+
+	if p.nowBuilding {
+		return errors.New("circular dependency involving \"" + p.path + "\"")
+	}
+	p.nowBuilding = true
+	defer func() { p.nowBuilding = false }()
+
+	rebuild := !p.goFile.exists || (p.mtime > p.goFile.mtime)
+	if !rebuild {
+		// Changes in imported files affect the generated code
+		imports, err := protoImports(p.path, p.parent.path, map[string]byte{p.path: 0})
+		if err != nil {
+			return err
+		}
+
+		for _, importPath := range imports {
+			fileInfo, err := os.Stat(importPath)
+			if (err == nil) && (fileInfo.ModTime().UnixNano() > p.goFile.mtime) {
+				rebuild = true
+				break
+			}
+		}
+	}
+
+	if rebuild {
+		err := mkdirAll(pathutil.Dir(p.goFile.path), 0777)
+		if err != nil {
+			return err
+		}
+
+		args := []string{protoc_exe.name, "-I.", "--go_out=_obj", p.name}
+		err = protoc_exe.runSimply(args, /*dir*/ p.parent.path, /*dontPrint*/ false)
+		if err != nil {
+			return err
+		}
+
+		p.goFile.UpdateFileInfo()
+		if !p.goFile.exists {
+			return errors.New("failed to generate \"" + p.goFile.path + "\"")
+		}
+	}
+
+	p.built = true
+	return nil
+}
+
+// Removes the generated Go file
+func (p *proto_file_t) Clean() error {
+	if (p.goFile != nil) && fileExists(p.goFile.path) {
+		if *flag_debug {
+			println("remove:", p.goFile.path)
+		}
+		err := os.Remove(p.goFile.path)
+		if err != nil {
+			return err
+		}
+
+		p.goFile.UpdateFileInfo()
+	}
+
+	return nil
+}
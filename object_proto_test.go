@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	pathutil "path"
+	"strings"
+	"testing"
+)
+
+// Writes its command-line to "protoc.log", and generates "DIR/FILE.pb.go" from FILE.proto.
+// The expected command-line is: protoc -I. --go_out=DIR FILE.proto
+const fakeProtoc = `
+echo "$@" >> protoc.log
+out=$(echo "$2" | sed 's/^--go_out=//')
+name=$(basename "$3" .proto)
+echo "package msg" > "$out/$name.pb.go"
+`
+
+func TestProtoFilesAreTranslatedByProtoc(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	writeTestScript(t, "bin/protoc", fakeProtoc)
+	defer prependToPath(t, pathutil.Join(dir, "bin"))()
+
+	savedProtoc := protoc_exe
+	protoc_exe = &Executable{name: "protoc"}
+	defer func() { protoc_exe = savedProtoc }()
+
+	savedGenerateMissingFiles := generateMissingFiles
+	generateMissingFiles = true
+	defer func() { generateMissingFiles = savedGenerateMissingFiles }()
+
+	// Zero-config mode, the import path of the package is "msg"
+	writeTestFile(t, "msg/msg.proto", "package msg;\nimport \"types.proto\";\n")
+	writeTestFile(t, "msg/types.proto", "package msg;\n")
+	writeTestFile(t, "msg/msg.go", "package msg\n")
+	*flag_srcroot = dir
+	err := os.Chdir("msg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := boot( /*updateTests*/ false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := ioutil.ReadFile("protoc.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"msg.proto", "types.proto"} {
+		if !strings.Contains(string(log), "-I. --go_out=_obj "+name+"\n") {
+			t.Errorf("protoc has not been run for %s, protoc.log:\n%s", name, log)
+		}
+	}
+
+	unit, isUnit := root.getObject_orNil([]string{"_obj", "msg" + o_ext}).(*compilation_unit_t)
+	if !isUnit {
+		t.Fatalf("expected the compilation unit \"_obj/msg%s\"", o_ext)
+	}
+
+	sources := make(map[string]byte)
+	for _, src := range unit.sources {
+		sources[src.Path()] = 0
+	}
+	for _, path := range []string{"msg.go", "_obj/msg.pb.go", "_obj/types.pb.go"} {
+		if _, found := sources[path]; !found {
+			t.Errorf("%s is not a source of the compilation unit, sources: %v", path, sources)
+		}
+	}
+}
+
+func TestProtoImports(t *testing.T) {
+	_, leave := enterTempDir(t)
+	defer leave()
+
+	writeTestFile(t, "a.proto", "import \"b.proto\";\nimport \"google/protobuf/descriptor.proto\";\n")
+	writeTestFile(t, "b.proto", "import \"c.proto\";\n")
+	writeTestFile(t, "c.proto", "import \"a.proto\";\n")
+
+	imports, err := protoImports("a.proto", /*includeDir*/ ".", map[string]byte{"a.proto": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files which do not exist are skipped, and cycles are broken
+	if strings.Join(imports, " ") != "b.proto c.proto" {
+		t.Errorf("expected imports [b.proto c.proto], got %v", imports)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	pathutil "path"
+	"strings"
+)
+
+// Represents a FILE.y.
+// The grammar is translated by goyacc into FILE.go.
+type yacc_grammar_t struct {
+	nop_object_t
+	parent      *dir_t
+	goFile      *go_file_t // The generated Go file, initially nil
+	built       bool
+	nowBuilding bool
+}
+
+var yacc_exe = &Executable{
+	name: "goyacc",
+}
+
+// The name of the file in which goyacc describes the parsing tables
+const yaccOutputFileName = "y.output"
+
+var yacc_fileHandler = &file_handler_t{
+	name:           "yacc",
+	description:    "yacc grammar",
+	phase:          FILE_HANDLER_AFTER_CONFIG,
+	optional:       true,
+	enabledDefault: true,
+	match: func(fileName string, fi os.FileInfo) bool {
+		return strings.HasSuffix(fileName, ".y")
+	},
+	create: func(entry entry_t, parent *dir_t) (object_t, error) {
+		return new_yacc_grammar(entry, parent), nil
+	},
+}
+
+// ==============
+// yacc_grammar_t
+// ==============
+
+func new_yacc_grammar(entry entry_t, parent *dir_t) *yacc_grammar_t {
+	y := &yacc_grammar_t{
+		nop_object_t: nop_object_t{entry},
+		parent:       parent,
+	}
+	project.yaccGrammars = append(project.yaccGrammars, y)
+	project.newObjects[y] = 0
+	return y
+}
+
+// Returns the options passed to goyacc (defined by 'Yacc' in a config file)
+func (y *yacc_grammar_t) options() []string {
+	return project.yaccOptions[y.path]
+}
+
+// Expects the file FILE.go generated from FILE.y
+func (y *yacc_grammar_t) InferObjects(updateTests bool) error {
+	if y.goFile != nil {
+		return nil
+	}
+
+	name := y.NameWithoutExtension() + ".go"
+
+	switch object := y.parent.getObject_orNil([]string{name}).(type) {
+	case nil:
+		y.goFile = new_go_file(new_entry_from_path(name, pathutil.Join(y.parent.path, name)), y.parent)
+		y.parent.add(y.goFile)
+	case *go_file_t:
+		y.goFile = object
+	default:
+		return errors.New("file \"" + object.Path() + "\" was expected to be generated from \"" + y.path + "\"")
+	}
+
+	if y.goFile.generator_orNil != nil {
+		return errors.New("file \"" + y.goFile.path + "\" is generated by multiple objects")
+	}
+	y.goFile.generator_orNil = y
+
+	return nil
+}
+
+func (y *yacc_grammar_t) PrintDependencies(w io.Writer) {
+	if y.goFile != nil {
+		fmt.Fprintf(w, "%s <-- [%s]\n", y.goFile.path, y.path)
+	}
+}
+
+func (y *yacc_grammar_t) Make() error {
+	if y.built || (y.goFile == nil) {
+		return nil
+	}
+
+	if y.nowBuilding {
+		return errors.New("circular dependency involving \"" + y.path + "\"")
+	}
+	y.nowBuilding = true
+	defer func() { y.nowBuilding = false }()
+
+	rebuild := !y.goFile.exists || (y.mtime > y.goFile.mtime)
+
+	if rebuild {
+		var args []string
+		args = append(args, yacc_exe.name)
+		args = append(args, y.options()...)
+		args = append(args, "-o", y.goFile.name, y.name)
+
+		err := yacc_exe.runSimply(args, /*dir*/ y.parent.path, /*dontPrint*/ false)
+		if err != nil {
+			return err
+		}
+
+		y.goFile.UpdateFileInfo()
+		if !y.goFile.exists {
+			return errors.New("failed to generate \"" + y.goFile.path + "\"")
+		}
+	}
+
+	y.built = true
+	return nil
+}
+
+// Removes the generated Go file and the description of the parsing tables
+func (y *yacc_grammar_t) Clean() error {
+	paths := []string{pathutil.Join(y.parent.path, yaccOutputFileName)}
+	if y.goFile != nil {
+		paths = append(paths, y.goFile.path)
+	}
+
+	for _, path := range paths {
+		if fileExists(path) {
+			if *flag_debug {
+				println("remove:", path)
+			}
+			err := os.Remove(path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if y.goFile != nil {
+		y.goFile.UpdateFileInfo()
+	}
+
+	return nil
+}
+
+// Checks for options which would conflict with the options passed by GOAM to goyacc
+func checkYaccOptions(options []string) error {
+	for _, option := range options {
+		if (option == "-o") || strings.HasPrefix(option, "-o=") {
+			return errors.New("the output file of goyacc cannot be changed")
+		}
+	}
+
+	return nil
+}
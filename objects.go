@@ -8,10 +8,6 @@ import (
 	pathutil "path"
 )
 
-// A set of objects.
-// This is a set, the map's value has no meaning.
-var newObjects = make(map[object_t]byte)
-
 type object_t interface {
 	Name() string
 	NameWithoutExtension() string
@@ -56,6 +52,21 @@ type config_file_t struct {
 	targetPackage_orEmpty string          // Empty string means the target package is unspecified
 	packageFiles_orNil    map[string]byte // A set of file names, each name ends with ".go".
 	// A nil value means "all Go files in the directory".
+	settings *config_settings_t // Settings inherited from parent directories, possibly overridden
+
+	// Options and environment variables consulted by the config file.
+	// Each item has the format "NAME=VALUE".
+	consultedOptions []string
+
+	// Files to embed into the package, defined by 'EmbedFiles'
+	embeds []*embed_spec_t
+
+	// Commands defined by 'BeforeBuild', 'AfterBuild' and 'AfterTest'
+	hooks [NUM_HOOK_KINDS][][]string
+
+	// Protocol buffer files to translate, defined by 'Protos'.
+	// A nil value means "all protocol buffer files in the directory".
+	protoPatterns []string
 }
 
 // Represents a FILE.o, FILE.8, FILE.6, etc
@@ -170,7 +181,7 @@ func new_config_file(entry entry_t, parent *dir_t) (*config_file_t, error) {
 		return nil, errors.New("directory \"" + parent.path + "\" contains multiple config files")
 	}
 
-	newObjects[config] = 0
+	project.newObjects[config] = 0
 	return config, nil
 }
 
@@ -181,7 +192,7 @@ func (f *config_file_t) UpdateFileSystemModel() {
 func (f *config_file_t) InferObjects(updateTests bool) error {
 	// Consistency check
 	if f.packageFiles_orNil != nil {
-		if len(f.targetPackage_orEmpty) == 0 {
+		if (len(f.targetPackage_orEmpty) == 0) && !f.parent.settings().haveImportPrefix() {
 			return errors.New("configuration file \"" + f.path + "\" specifies package files, but does not specify the package")
 		}
 	}
@@ -194,7 +205,24 @@ func (f *config_file_t) PrintDependencies(w io.Writer) {
 }
 
 func (f *config_file_t) Info(info *info_t) {
-	return
+	if len(f.consultedOptions) > 0 {
+		info.configs[f] = 0
+	}
+}
+
+func (f *config_file_t) consultOption(name, value string, isDefault bool) {
+	option := name + "=\"" + value + "\""
+	if isDefault {
+		option += " (default)"
+	}
+
+	for _, x := range f.consultedOptions {
+		if x == option {
+			return
+		}
+	}
+
+	f.consultedOptions = append(f.consultedOptions, option)
 }
 
 func (f *config_file_t) Make() error {
@@ -239,7 +267,7 @@ func new_compilation_unit(entry entry_t, parent *dir_t) *compilation_unit_t {
 		parent:  parent,
 		sources: make([]go_source_code_t, 0, 8),
 	}
-	newObjects[u] = 0
+	project.newObjects[u] = 0
 	return u
 }
 
@@ -360,6 +388,7 @@ func (u *compilation_unit_t) Make() error {
 		{
 			args = append(args, goCompiler_exe.name)
 			args = append(args, goCompiler_flags...)
+			args = append(args, u.parent.settings().compilerFlags...)
 			args = append(args, "-o")
 			args = append(args, u.path)
 			if libIncludePaths != nil {
@@ -367,6 +396,9 @@ func (u *compilation_unit_t) Make() error {
 					args = append(args, "-I", incPath.path)
 				}
 			}
+			for _, root := range libSearchRoots() {
+				args = append(args, "-I", root)
+			}
 			for _, src := range u.sources {
 				args = append(args, src.Path())
 			}
@@ -426,7 +458,7 @@ func new_library(entry entry_t, parent *dir_t) *library_t {
 		parent:  parent,
 		sources: make([]*compilation_unit_t, 0, 2),
 	}
-	newObjects[l] = 0
+	project.newObjects[l] = 0
 	return l
 }
 
@@ -513,6 +545,11 @@ func (l *library_t) Make() error {
 	}
 
 	if rebuild {
+		err := l.runHooks(HOOK_BEFORE_BUILD)
+		if err != nil {
+			return err
+		}
+
 		if l.makefile_orNil == nil {
 			err := l.parent.mkdir_ifDoesNotExist()
 			if err != nil {
@@ -556,12 +593,30 @@ func (l *library_t) Make() error {
 				return errors.New("failed to build \"" + l.path + "\"")
 			}
 		}
+
+		err = l.runHooks(HOOK_AFTER_BUILD)
+		if err != nil {
+			return err
+		}
 	}
 
 	l.built = true
 	return nil
 }
 
+// Runs the hooks defined for the library.
+// Libraries built for tests do not run any hooks.
+func (l *library_t) runHooks(kind int) error {
+	if l.partOfATest {
+		return nil
+	}
+
+	// If the import path cannot be determined, it is passed to the hooks as an empty string
+	importPath, _ := l.parent.hooksDir().targetPackage()
+
+	return runHooks(kind, l.parent, &hook_artifact_t{path: l.path, kind: "library", importPath: importPath})
+}
+
 func (l *library_t) MakeTests() error {
 	return nil
 }
@@ -651,7 +706,7 @@ func new_executable(entry entry_t, parent *dir_t) *executable_t {
 		parent:  parent,
 		sources: make([]*compilation_unit_t, 0, 2),
 	}
-	newObjects[e] = 0
+	project.newObjects[e] = 0
 	return e
 }
 
@@ -826,6 +881,18 @@ func (e *executable_t) doMake(installMode bool) error {
 	}
 
 	if rebuild || installMode {
+		var target string
+		if !installMode {
+			target = e.path
+		} else {
+			target = pathutil.Join(exeInstallDir, e.name)
+		}
+
+		err = e.runHooks(HOOK_BEFORE_BUILD, target)
+		if err != nil {
+			return err
+		}
+
 		if e.makefile_orNil == nil {
 			err = e.parent.mkdir_ifDoesNotExist()
 			if err != nil {
@@ -834,19 +901,16 @@ func (e *executable_t) doMake(installMode bool) error {
 
 			var args []string
 			{
-				var target string
-				if !installMode {
-					target = e.path
-				} else {
-					target = pathutil.Join(exeInstallDir, e.name)
-				}
-
 				args = append(args, goLinker_exe.name)
+				args = append(args, e.parent.settings().linkerFlags...)
 				args = append(args, "-o")
 				args = append(args, target)
 				for _, incPath := range libIncludePaths {
 					args = append(args, "-L", incPath.path)
 				}
+				for _, root := range libSearchRoots() {
+					args = append(args, "-L", root)
+				}
 				for _, src := range e.sources {
 					args = append(args, src.Path())
 				}
@@ -880,11 +944,27 @@ func (e *executable_t) doMake(installMode bool) error {
 				return errors.New("failed to build \"" + e.path + "\"")
 			}
 		}
+
+		err = e.runHooks(HOOK_AFTER_BUILD, target)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// Runs the 'BeforeBuild' or 'AfterBuild' hooks defined for the executable.
+// The 'target' is the path of the built executable.
+// Test executables do not run these hooks.
+func (e *executable_t) runHooks(kind int, target string) error {
+	if len(e.testImportPath_orEmpty) != 0 {
+		return nil
+	}
+
+	return runHooks(kind, e.parent, &hook_artifact_t{path: target, kind: "executable"})
+}
+
 func (e *executable_t) Make() error {
 	// If 'e' is not a test/benchmark
 	if len(e.testImportPath_orEmpty) == 0 {
@@ -930,6 +1010,17 @@ func (e *executable_t) RunTests(testPattern, benchPattern string, errors *[]erro
 		if err != nil {
 			*errors = append(*errors, err)
 		}
+
+		artifact := &hook_artifact_t{
+			path:       e.path,
+			kind:       "test",
+			importPath: e.testImportPath_orEmpty,
+			testFailed: (err != nil),
+		}
+		err = runHooks(HOOK_AFTER_TEST, e.parent, artifact)
+		if err != nil {
+			*errors = append(*errors, err)
+		}
 	}
 }
 
@@ -0,0 +1,141 @@
+package main
+
+import (
+	eval "bitbucket.org/binet/go-eval/pkg/eval"
+)
+
+// The state created by reading a directory tree and evaluating its config files.
+// All state which belongs to a particular project is kept in this structure,
+// so that GOAM can boot another directory tree (see 'buildWithGoam')
+// without sharing any of it with the current project.
+type project_state_t struct {
+	currentConfig *config_file_t
+	currentWorld  *eval.World
+
+	// The stack of script files being evaluated.
+	// The 1st element is the config file, the other elements are included files.
+	includeStack []string
+
+	// The settings in effect in directories without any config file
+	defaultSettings *config_settings_t
+
+	// Mapping between [the path of an executable] and [the paths of Go files from which to build the executable]
+	executable2sources map[string][]string
+	source2executable  map[string]string
+
+	pendingExecutables []*pending_executable_t
+
+	// Set of directories to ignore.
+	// This is a set, the values of this hash-map have no meaning.
+	ignoredDirs map[string]byte
+
+	ignoredDirPatterns []*ignored_dir_pattern_t
+
+	// Set of files for which gofmt is disabled.
+	// This is a set, the values of this hash-map have no meaning.
+	disabledGoFmt map[string]byte
+
+	// Mapping between [the path of a grammar] and [the options passed to goyacc]
+	yaccOptions map[string][]string
+
+	// Whether config files are evaluated by "goam check-config".
+	// In this mode, errors do not stop the evaluation of config files.
+	checkingConfig bool
+
+	// Problems found by "goam check-config".
+	// Each item has the format "FILE:LINE:COLUMN: MESSAGE",
+	// or "FILE: MESSAGE" if the position of the call is not known.
+	configDiagnostics []string
+
+	// The position of the function call currently being evaluated,
+	// "FILE:LINE:COLUMN" or "FILE"
+	currentCallPosition string
+
+	// Script path --> calls
+	scriptCalls map[string]*script_calls_t
+
+	deferredConfigChecks []config_check_t
+
+	// All generators defined by '//goam:generate' directives
+	goGenerators []*go_generator_t
+
+	// All protocol buffer files found in the project
+	protoFiles []*proto_file_t
+
+	// All grammars found in the project
+	yaccGrammars []*yacc_grammar_t
+
+	// All commands defined by config files
+	commands []*command_t
+
+	// Output path --> command
+	commands_byOutput map[string]*command_t
+
+	importPathResolutionTable      map[string]*package_resolution_t
+	importPathResolutionTable_test map[string]*package_resolution_t
+
+	installationCommands                  []installation_command_t
+	installationCommands_bySrcPath        map[string]installation_command_t
+	installationCommands_packagesByImport map[string]*install_package_t
+
+	// A set of objects.
+	// This is a set, the map's value has no meaning.
+	newObjects map[object_t]byte
+
+	// Whether GOAM is running in zero-config mode (option -srcroot).
+	// In zero-config mode, the import path of a directory is
+	// the directory's path relative to the source root,
+	// and an executable is named after the directory containing it.
+	zeroConfig bool
+
+	// Mapping between [the path of a directory] and [the import path inferred for the directory]
+	inferredPackages map[string]string
+
+	// Mapping between [the path of an executable] and [the directory the executable was inferred from]
+	inferredExecutables map[string]string
+
+	// All remote packages defined by configuration files
+	remotePackages              []*remote_package_t
+	remotePackages_byImport     map[string]*remote_package_t
+	remotePackages_byRepository map[string]*remote_package_t
+
+	// Mapping between [an import path] and [the local directory replacing the remote package
+	// providing the import path]. The directories are absolute paths.
+	replacements map[string]string
+
+	// The library search roots of the project which builds this project
+	// with GOAM (see 'buildWithGoam'), as absolute paths
+	parentLibSearchRoots []string
+
+	// Repository path --> entry in the lock file
+	lockEntries  map[string]*lock_entry_t
+	lockFileRead bool
+}
+
+// The state of the current project
+var project = new_project_state()
+
+// Creates the state of a project which has not been read yet
+func new_project_state() *project_state_t {
+	return &project_state_t{
+		defaultSettings:                       &config_settings_t{},
+		executable2sources:                    make(map[string][]string),
+		source2executable:                     make(map[string]string),
+		ignoredDirs:                           make(map[string]byte),
+		disabledGoFmt:                         make(map[string]byte),
+		yaccOptions:                           make(map[string][]string),
+		scriptCalls:                           make(map[string]*script_calls_t),
+		commands_byOutput:                     make(map[string]*command_t),
+		importPathResolutionTable:             make(map[string]*package_resolution_t),
+		importPathResolutionTable_test:        make(map[string]*package_resolution_t),
+		installationCommands_bySrcPath:        make(map[string]installation_command_t),
+		installationCommands_packagesByImport: make(map[string]*install_package_t),
+		newObjects:                            make(map[object_t]byte),
+		inferredPackages:                      make(map[string]string),
+		inferredExecutables:                   make(map[string]string),
+		remotePackages_byImport:               make(map[string]*remote_package_t),
+		remotePackages_byRepository:           make(map[string]*remote_package_t),
+		replacements:                          make(map[string]string),
+		lockEntries:                           make(map[string]*lock_entry_t),
+	}
+}
@@ -7,9 +7,59 @@ import (
 	"strings"
 )
 
+// Enables zero-config mode if the option -srcroot was specified
+func initZeroConfig() error {
+	if len(*flag_srcroot) == 0 {
+		return nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	srcRoot := *flag_srcroot
+	if !pathutil.IsAbs(srcRoot) {
+		srcRoot = pathutil.Join(cwd, srcRoot)
+	}
+	srcRoot = pathutil.Clean(srcRoot)
+
+	var prefix string
+	switch {
+	case cwd == srcRoot:
+		prefix = ""
+	case strings.HasPrefix(cwd, srcRoot+"/"):
+		prefix = cwd[len(srcRoot)+1:]
+	case srcRoot == "/":
+		prefix = cwd[1:]
+	default:
+		return errors.New("the current directory is not under the source root \"" + srcRoot + "\"")
+	}
+
+	if *flag_debug {
+		println("zero-config mode, import prefix:", "\""+prefix+"\"")
+	}
+
+	project.zeroConfig = true
+	project.defaultSettings.importPrefix = prefix
+	project.defaultSettings.importPrefixDir = "."
+
+	return nil
+}
+
 func readDir() (*dir_t, error) {
 	name := "."
 
+	err := initZeroConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ignoreDepsRoot()
+	if err != nil {
+		return nil, err
+	}
+
 	fileInfo, err := os.Lstat(name)
 	if err != nil {
 		return nil, err
@@ -24,6 +74,16 @@ func readDir() (*dir_t, error) {
 		return nil, err
 	}
 
+	err = checkIgnoredDirPatterns()
+	if err != nil {
+		return nil, err
+	}
+
+	err = expandPendingExecutables()
+	if err != nil {
+		return nil, err
+	}
+
 	return dir, nil
 }
 
@@ -49,6 +109,9 @@ func readDir_internal(dir *dir_t) error {
 	objects := make([]object_t, listSize)
 	subdirs := make([]*dir_t, listSize)
 
+	// Files which have not been identified before evaluating the config file
+	var unidentified []os.FileInfo
+
 	numObjects := 0
 	numSubdirs := 0
 	numTemporarySubdirs := 0
@@ -63,6 +126,14 @@ func readDir_internal(dir *dir_t) error {
 			continue
 		}
 
+		// The vendor directory in the root directory is created by "goam vendor"
+		if (dir.parent_orNil == nil) && (entry.Name() == vendorDirName) && entry.IsDir() {
+			if *flag_debug {
+				println("ignore (vendored packages):", pathutil.Join(dir.path, entry.Name()))
+			}
+			continue
+		}
+
 		var object object_t
 		var err error
 
@@ -71,7 +142,10 @@ func readDir_internal(dir *dir_t) error {
 			object, err = new_dir(new_entry(pathutil.Join(dir.path, entry.Name()), entry), dir), nil
 
 		default:
-			object, err = identifyFile(pathutil.Join(dir.path, entry.Name()), entry, dir)
+			object, err = identifyFile(pathutil.Join(dir.path, entry.Name()), entry, dir, FILE_HANDLER_BEFORE_CONFIG)
+			if (err == nil) && (object == nil) {
+				unidentified = append(unidentified, entry)
+			}
 		}
 
 		if err != nil {
@@ -96,7 +170,7 @@ func readDir_internal(dir *dir_t) error {
 
 	dir.objects = objects
 
-	if dir.parent_orNil == nil {
+	if (dir.parent_orNil == nil) && !project.zeroConfig {
 		// The presence of sub-directories requires a config file or a Makefile
 		if ((numSubdirs - numTemporarySubdirs) > 0) && (dir.config_orNil == nil) && (dir.makefile_orNil == nil) {
 			return errors.New("the root directory has one or more user sub-directories," +
@@ -112,14 +186,37 @@ func readDir_internal(dir *dir_t) error {
 		}
 	}
 
+	// Identify the remaining files, the config file might have enabled or disabled some file types
+	for _, entry := range unidentified {
+		object, err := identifyFile(pathutil.Join(dir.path, entry.Name()), entry, dir, FILE_HANDLER_AFTER_CONFIG)
+		if err != nil {
+			return err
+		}
+		if object != nil {
+			dir.add(object)
+		}
+	}
+
 	// Dive into sub-directories
 	for _, subdir := range subdirs {
-		if _, ignore := ignoredDirs[pathutil.Clean(subdir.path)]; ignore {
+		if _, ignore := project.ignoredDirs[pathutil.Clean(subdir.path)]; ignore {
 			if *flag_debug {
 				println("do not dive into:", subdir.path)
 			}
 			continue
 		}
+		if !subdir.isTemporary() && isIgnoredByPattern(subdir) {
+			if *flag_debug {
+				println("do not dive into (pattern):", subdir.path)
+			}
+			continue
+		}
+		if !subdir.isTemporary() && dir.ignoresDirName(subdir) {
+			if *flag_debug {
+				println("do not dive into (name pattern):", subdir.path)
+			}
+			continue
+		}
 
 		err = readDir_internal(subdir)
 		if err != nil {
@@ -130,61 +227,6 @@ func readDir_internal(dir *dir_t) error {
 	return nil
 }
 
-func identifyFile(path string, fi os.FileInfo, parent *dir_t) (object_t, error) {
-	var entry entry_t = new_entry(path, fi)
-
-	if strings.HasSuffix(fi.Name(), "_test.go") {
-		if *flag_debug {
-			println("go test:", path)
-		}
-		return new_go_test(entry, parent), nil
-	}
-
-	if strings.HasSuffix(fi.Name(), ".go") {
-		if *flag_debug {
-			println("go source code:", path)
-		}
-		return new_go_file(entry, parent), nil
-	}
-
-	if (len(fi.Name()) == len(configFileName)) && (strings.ToLower(fi.Name()) == strings.ToLower(configFileName)) {
-		if *flag_debug {
-			println("config file:", path)
-		}
-		return new_config_file(entry, parent)
-	}
-
-	if fi.Name() == "Makefile" {
-		if *flag_debug {
-			println("makefile:", path)
-		}
-		return new_makefile(entry, parent)
-	}
-
-	if isCompilationUnit(fi.Name()) {
-		if *flag_debug {
-			println("compilation unit:", path)
-		}
-		return new_compilation_unit(entry, parent), nil
-	}
-
-	if strings.HasSuffix(fi.Name(), ".a") {
-		if *flag_debug {
-			println("library:", path)
-		}
-		return new_library(entry, parent), nil
-	}
-
-	if (fi.Mode().Perm() & 0100) != 0 {
-		if *flag_debug {
-			println("executable:", path)
-		}
-		return new_executable(entry, parent), nil
-	}
-
-	return nil, nil
-}
-
 func isCompilationUnit(path string) bool {
 	ext := pathutil.Ext(path)
 	return (ext == ".o") || (ext == ".5") || (ext == ".6") || (ext == ".8")
@@ -12,20 +12,69 @@ import (
 const (
 	GITHUB = iota
 	BITBUCKET
+	GIT
+	HG
+	SVN
+	BZR
+	LOCAL
 )
 
 type remote_package_t struct {
 	importPaths []string
 	repository  repository_t
-	installCmd  []string
+	installCmd  []string // Empty if the package is built by GOAM itself
+	revision    string   // A tag, branch or commit, or an empty string
+
+	// The repository defined by the config files if 'repository'
+	// is a local directory replacing it, see 'Replace'
+	originalRepository_orNil repository_t
+
+	// Set by 'fetch'
+	localDir          string // The directory in which the installation command runs
+	libRoot_orEmpty   string // Where to install libraries if different from 'libInstallRoot'
+	reportToDashboard bool
+	verified          bool // Whether the checksum of the local copy has been verified
+	fetched           bool // Whether the local copy has been updated to the requested revision
+
+	// Remote packages defined by the config files in the repository of the package
+	dependencies []*remote_package_t
 }
 
 type repository_t interface {
 	Kind() int
 	KindString() string
+	KindName() string // The repository type used in config files and in the lock file
 	Path() string
 	DashboardPath() string
-	CloneOrUpdate() (string, bool, error)
+	Tools() []*Executable
+
+	// The path of the local copy of the repository
+	ProjectDir() string
+
+	// Returns the commit ID checked out in the local copy
+	CurrentRevision() (string, error)
+
+	// Returns the commit ID denoted by the revision (a tag, branch or commit)
+	// in the local copy, without downloading changes. A branch denotes the newest
+	// commit of the branch downloaded from the remote repository.
+	ResolveRevision(revision string) (string, error)
+
+	// Returns whether files in the local copy have been modified
+	HasLocalModifications() (bool, error)
+
+	// Downloads changes without updating the local files, and returns the number
+	// of commits in the revision (or in the default branch if the revision is empty)
+	// which are missing in the local copy
+	CommitsBehind(revision string) (int, error)
+
+	// Returns the files under version control in the local copy,
+	// relative to the directory of the local copy
+	TrackedFiles() ([]string, error)
+
+	// Clones the repository or updates the local copy, and checks out
+	// the revision (a tag, branch or commit). An empty revision means
+	// the default branch. Returns the path of the local copy.
+	CloneOrUpdate(revision string) (string, bool, error)
 }
 
 type repository_github_t struct {
@@ -36,14 +85,147 @@ type repository_bitbucket_t struct {
 	repositoryPath string
 }
 
-// All remote packages defined by configuration files
-var remotePackages []*remote_package_t = nil
-var remotePackages_byImport = make(map[string]*remote_package_t)
-var remotePackages_byRepository = make(map[string]*remote_package_t)
-
 func installAllRemotePackages() error {
-	for _, remotePackage := range remotePackages {
-		err := remotePackage.Install()
+	return installRemotePackages( /*updateAll*/ false, /*repository_orEmpty*/ "")
+}
+
+// Installs all remote packages from the newest revisions,
+// ignoring the revisions recorded in the lock file
+func updateAllRemotePackages() error {
+	if *flag_offline {
+		return errors.New("remote packages cannot be updated in offline mode")
+	}
+
+	return installRemotePackages( /*updateAll*/ true, /*repository_orEmpty*/ "")
+}
+
+// Installs the remote package provided by the repository from the newest revision.
+// Other remote packages are installed only if they need to be installed.
+func updateRemotePackage(repositoryPath string) error {
+	if *flag_offline {
+		return errors.New("remote packages cannot be updated in offline mode")
+	}
+
+	if _, defined := project.remotePackages_byRepository[repositoryPath]; !defined {
+		return errors.New("repository \"" + repositoryPath + "\" is not defined by the config files")
+	}
+
+	return installRemotePackages( /*updateAll*/ false, repositoryPath)
+}
+
+// Enumeration of the states of remote packages while searching for dependencies
+const (
+	DEPS_NOT_VISITED = iota
+	DEPS_VISITING
+	DEPS_VISITED
+)
+
+// Installs the remote packages which need to be installed together with their dependencies.
+// All remote packages are updated if 'updateAll' is true, otherwise only the package
+// provided by the repository 'repository_orEmpty' is updated. The dependencies of
+// a remote package are the remote packages defined by the config files
+// in its repository. Dependencies are installed before the packages depending on them.
+// The dependencies of packages which do not need to be installed are searched for
+// as well, so that all remote packages are verified and recorded in the lock file.
+func installRemotePackages(updateAll bool, repository_orEmpty string) error {
+	err := readLockFile()
+	if err != nil {
+		return err
+	}
+
+	update := func(p *remote_package_t) bool {
+		return updateAll || (p.repository.Path() == repository_orEmpty)
+	}
+
+	// Check for missing version control tools before cloning anything.
+	// Vendored packages, and all packages in offline mode, are installed without cloning.
+	if !*flag_offline {
+		var tools []*Executable
+		for _, remotePackage := range project.remotePackages {
+			if update(remotePackage) || (remotePackage.installationRequired() && !remotePackage.isVendored()) {
+				tools = append(tools, remotePackage.repository.Tools()...)
+			}
+		}
+
+		err = lookupExecutables(tools)
+		if err != nil {
+			return err
+		}
+	}
+
+	state := make(map[*remote_package_t]int)
+	var order []*remote_package_t
+
+	// The repositories being visited, used for reporting cycles
+	var stack []string
+
+	var visit func(p *remote_package_t) error
+	visit = func(p *remote_package_t) error {
+		switch state[p] {
+		case DEPS_VISITED:
+			return nil
+
+		case DEPS_VISITING:
+			cycle := []string{p.repository.Path()}
+			for i := len(stack) - 1; i >= 0; i-- {
+				cycle = append([]string{stack[i]}, cycle...)
+				if stack[i] == p.repository.Path() {
+					break
+				}
+			}
+			return errors.New("cyclic dependency between repositories: " + strings.Join(cycle, " -> "))
+		}
+
+		state[p] = DEPS_VISITING
+		stack = append(stack, p.repository.Path())
+
+		install := update(p) || p.installationRequired()
+		if install {
+			err := p.fetch(update(p))
+			if err != nil {
+				return err
+			}
+		} else {
+			// The package is installed, but its dependencies have to be known
+			// in order to be checked and recorded in the lock file
+			p.localDir = p.sourceDir()
+		}
+
+		if len(p.localDir) != 0 {
+			err := p.findDependencies()
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, dependency := range p.dependencies {
+			err = visit(dependency)
+			if err != nil {
+				return err
+			}
+		}
+
+		stack = stack[0 : len(stack)-1]
+		state[p] = DEPS_VISITED
+		if install {
+			order = append(order, p)
+		}
+		return nil
+	}
+
+	// The list of remote packages grows while searching for dependencies
+	rootPackages := make([]*remote_package_t, len(project.remotePackages))
+	copy(rootPackages, project.remotePackages)
+
+	for _, remotePackage := range rootPackages {
+		err = visit(remotePackage)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, remotePackage := range order {
+		err = remotePackage.install()
 		if err != nil {
 			return err
 		}
@@ -56,14 +238,68 @@ func installAllRemotePackages() error {
 // remote_package_t
 // ================
 
-func new_remotePackage(importPaths []string, repository repository_t, installCmd []string) *remote_package_t {
-	return &remote_package_t{importPaths, repository, installCmd}
+func new_remotePackage(importPaths []string, repository repository_t, installCmd []string, revision string) *remote_package_t {
+	return &remote_package_t{
+		importPaths: importPaths,
+		repository:  repository,
+		installCmd:  installCmd,
+		revision:    revision,
+	}
+}
+
+// Finds or creates the remote package provided by the repository.
+// A repository can be defined multiple times, but the definitions have to be the same.
+func defineRemotePackage(importPaths []string, kind int, repositoryPath, revision string, installCmd []string) (*remote_package_t, error) {
+	var remotePkg *remote_package_t
+	if remotePkg1, alreadyExists := project.remotePackages_byRepository[repositoryPath]; alreadyExists {
+		if remotePkg1.declaredRepository().Kind() != kind {
+			return nil, errors.New("repository \"" + repositoryPath + "\" redefined with a different repository type")
+		}
+
+		if len(remotePkg1.installCmd) != len(installCmd) {
+			return nil, errors.New("repository \"" + repositoryPath + "\" redefined with a different installation command")
+		}
+		for i := 0; i < len(installCmd); i++ {
+			if remotePkg1.installCmd[i] != installCmd[i] {
+				return nil, errors.New("repository \"" + repositoryPath + "\" redefined with a different installation command")
+			}
+		}
+
+		if remotePkg1.revision != revision {
+			return nil, errors.New("repository \"" + repositoryPath + "\" redefined with a different revision" +
+				" (\"" + remotePkg1.revision + "\" and \"" + revision + "\")")
+		}
+
+		remotePkg = remotePkg1
+	} else {
+		remotePkg = new_remotePackage(importPaths, new_repository(kind, repositoryPath), installCmd, revision)
+		project.remotePackages = append(project.remotePackages, remotePkg)
+		project.remotePackages_byRepository[repositoryPath] = remotePkg
+
+		err := remotePkg.applyReplacements()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Add to 'remotePackages_byImport'
+	for _, importPath := range importPaths {
+		if remotePkg1, exists := project.remotePackages_byImport[importPath]; exists {
+			if remotePkg1 != remotePkg {
+				return nil, errors.New("import path \"" + importPath + "\" maps to multiple distinct repositories")
+			}
+		} else {
+			project.remotePackages_byImport[importPath] = remotePkg
+		}
+	}
+
+	return remotePkg, nil
 }
 
 func (p *remote_package_t) Check() error {
 	// Check for meaningless remote-package definitions
 	for _, importPath := range p.importPaths {
-		if pkg, defined := importPathResolutionTable[importPath]; defined {
+		if pkg, defined := project.importPathResolutionTable[importPath]; defined {
 			return errors.New("import path \"" + importPath + "\" maps to library \"" + pkg.lib.path + "\"," +
 				" there is no need to define a remote package")
 		}
@@ -72,43 +308,289 @@ func (p *remote_package_t) Check() error {
 	return nil
 }
 
-func (p *remote_package_t) Install() error {
-	var err error
+// Returns whether some of the import paths provided by 'p' cannot be resolved,
+// or whether the local copy of the repository differs from the revision in the lock file
+// or from the revision requested by the config files.
+// The revision of a vendored package is determined by the vendored sources.
+// A replaced package is always installed, the local directory might have been modified.
+func (p *remote_package_t) installationRequired() bool {
+	if p.isReplaced() {
+		return true
+	}
 
-	installationRequired := false
 	for _, importPath := range p.importPaths {
-		_, err = resolvePackage(importPath, /*test*/ false)
+		_, err := resolvePackage(importPath, /*test*/ false)
 		if err != nil {
-			installationRequired = true
-			break
+			return true
 		}
 	}
 
-	if installationRequired {
-		fmt.Fprintf(os.Stdout, "Installing remote package \""+p.repository.Path()+"\"\n")
+	if p.isVendored() || !fileExists(p.repository.ProjectDir()) {
+		return false
+	}
+
+	if entry, locked := project.lockEntries[p.repository.Path()]; locked {
+		if (entry.kind != p.repository.KindName()) || (entry.revision != p.revision) {
+			// The config files request a revision different from the one in the lock file
+			return true
+		}
+
+		currentCommit, err := p.repository.CurrentRevision()
+		return (err != nil) || (currentCommit != entry.commit)
+	}
+
+	if len(p.revision) != 0 {
+		return !p.atRequestedRevision()
+	}
+
+	return false
+}
+
+// Returns whether the local copy is at the commit denoted by the revision requested
+// by the config files. A pinned branch is compared with the newest commit of the branch
+// downloaded from the remote repository.
+func (p *remote_package_t) atRequestedRevision() bool {
+	requestedCommit, err := p.repository.ResolveRevision(p.revision)
+	if err != nil {
+		return false
+	}
+
+	currentCommit, err := p.repository.CurrentRevision()
+	return (err == nil) && (currentCommit == requestedCommit)
+}
+
+// Returns the entry of the package in the lock file.
+// An entry recorded for a different kind of repository is ignored.
+func (p *remote_package_t) lockEntry() (*lock_entry_t, bool) {
+	entry, locked := project.lockEntries[p.repository.Path()]
+	if !locked || (entry.kind != p.repository.KindName()) {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// Returns the commit recorded in the lock file, or an empty string.
+// The lock entry is ignored if the configuration has requested a different revision.
+func (p *remote_package_t) lockedCommit() string {
+	entry, locked := p.lockEntry()
+	if !locked || (entry.revision != p.revision) {
+		return ""
+	}
+
+	return entry.commit
+}
 
-		projectPath, reportToDashboard, err := p.repository.CloneOrUpdate()
+// Gets the sources of the package: the local directory replacing the package,
+// the vendored sources, the existing local copy in offline mode, or the local copy
+// updated to the revision in the lock file (or to the newest revision if 'update' is true)
+func (p *remote_package_t) fetch(update bool) error {
+	switch {
+	case p.isReplaced():
+		fmt.Fprintf(os.Stdout, "Installing remote package \""+p.originalRepository_orNil.Path()+"\""+
+			" from the local directory \""+p.repository.Path()+"\"\n")
+
+		var err error
+		p.localDir, _, err = p.repository.CloneOrUpdate( /*revision*/ "")
 		if err != nil {
 			return err
 		}
 
-		exe := &Executable{name: p.installCmd[0]}
-		err = exe.runSimply(p.installCmd, /*dir*/ projectPath, /*dontPrint*/ false)
+		cwd, err := os.Getwd()
 		if err != nil {
 			return err
 		}
 
-		// Check that the command actually installed the package
-		for _, importPath := range p.importPaths {
-			_, err = resolvePackage(importPath, /*test*/ false)
+		// Libraries are installed into the library root of the replacement
+		p.libRoot_orEmpty = pathutil.Join(cwd, p.replacementLibRoot())
+	case !update && p.isVendored():
+		fmt.Fprintf(os.Stdout, "Installing vendored package \""+p.repository.Path()+"\"\n")
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+
+		// Libraries are installed into the vendor directory
+		p.localDir = p.vendorPath()
+		p.libRoot_orEmpty = pathutil.Join(cwd, vendorLibRoot())
+
+	case *flag_offline:
+		projectDir := p.repository.ProjectDir()
+		if !fileExists(projectDir) {
+			return errors.New("remote package \"" + p.repository.Path() + "\" is not available" +
+				" in offline mode (\"" + projectDir + "\" does not exist)")
+		}
+
+		if lockedCommit := p.lockedCommit(); len(lockedCommit) != 0 {
+			currentCommit, err := p.repository.CurrentRevision()
 			if err != nil {
-				return errors.New("remote package \"" + p.repository.Path() + "\"" +
-					" failed to provide the library \"" + importPath + "\"")
+				return err
+			}
+			if currentCommit != lockedCommit {
+				return errors.New("remote package \"" + p.repository.Path() + "\": the local copy is at revision " +
+					currentCommit + ", but " + lockFileName + " requires revision " + lockedCommit +
+					" which cannot be checked out in offline mode")
 			}
+		} else if (len(p.revision) != 0) && !p.atRequestedRevision() {
+			return errors.New("remote package \"" + p.repository.Path() + "\": the local copy is not at" +
+				" the requested revision \"" + p.revision + "\" which cannot be checked out in offline mode")
 		}
 
-		if reportToDashboard {
-			maybeReportToDashboard(p.repository.DashboardPath())
+		fmt.Fprintf(os.Stdout, "Installing remote package \""+p.repository.Path()+"\" (offline)\n")
+
+		p.localDir = projectDir
+
+		err := p.verifyChecksum()
+		if err != nil {
+			return err
+		}
+		p.verified = true
+
+	default:
+		revision := p.revision
+		if !update && (len(p.lockedCommit()) != 0) {
+			revision = p.lockedCommit()
+		}
+
+		if len(revision) == 0 {
+			fmt.Fprintf(os.Stdout, "Installing remote package \""+p.repository.Path()+"\"\n")
+		} else {
+			fmt.Fprintf(os.Stdout, "Installing remote package \""+p.repository.Path()+"\" (revision "+revision+")\n")
+		}
+
+		// Dependencies have not been known before cloning the repositories depending on them
+		err := lookupExecutables(p.repository.Tools())
+		if err != nil {
+			return err
+		}
+
+		p.localDir, p.reportToDashboard, err = p.repository.CloneOrUpdate(revision)
+		if err != nil {
+			return err
+		}
+		p.fetched = true
+
+		// Verify the sources before running the installation command
+		err = p.verifyChecksum()
+		if err != nil {
+			return err
+		}
+		p.verified = true
+	}
+
+	return nil
+}
+
+// Returns the directory containing the sources from which the package has been installed:
+// the vendored sources or the local copy of the repository.
+// Returns an empty string if the sources do not exist.
+func (p *remote_package_t) sourceDir() string {
+	if p.isVendored() {
+		return p.vendorPath()
+	}
+
+	if fileExists(p.repository.ProjectDir()) {
+		return p.repository.ProjectDir()
+	}
+
+	return ""
+}
+
+// Evaluates the config files in the sources of the package,
+// and registers the remote packages defined by the config files
+func (p *remote_package_t) findDependencies() error {
+	if !fileExists(pathutil.Join(p.localDir, configFileName)) {
+		return nil
+	}
+
+	definitions, err := listRemotePackages(p.localDir)
+	if err != nil {
+		return errors.New("remote package \"" + p.repository.Path() + "\": " + err.Error())
+	}
+
+	p.dependencies = nil
+	for _, def := range definitions {
+		// A relative path of a local repository is relative to the sources of the package
+		repositoryPath := def.repositoryPath
+		if (def.kind == LOCAL) && !pathutil.IsAbs(repositoryPath) {
+			repositoryPath = pathutil.Join(p.localDir, repositoryPath)
+		}
+
+		dependency, err := defineRemotePackage(def.importPaths, def.kind, repositoryPath, def.revision, def.installCmd)
+		if err != nil {
+			return errors.New("remote package \"" + p.repository.Path() + "\": version conflict: " + err.Error())
+		}
+
+		if *flag_debug {
+			println("remote dependency:", p.repository.Path(), "-->", dependency.repository.Path())
+		}
+
+		p.dependencies = append(p.dependencies, dependency)
+	}
+
+	return nil
+}
+
+// Runs the installation command in the directory obtained by 'fetch'.
+// A package without an installation command is built by GOAM itself.
+func (p *remote_package_t) install() error {
+	var err error
+	if len(p.installCmd) == 0 {
+		if !canBuildWithGoam(p.localDir) {
+			return errors.New("remote package \"" + p.repository.Path() + "\" has no installation command," +
+				" and its sources contain neither " + configFileName + " nor a Makefile")
+		}
+
+		err = p.buildWithGoam(p.localDir, p.libRoot_orEmpty)
+		if err == nil {
+			err = p.checkInstalled()
+		}
+	} else {
+		var env []string
+		if len(p.libRoot_orEmpty) != 0 {
+			env = []string{"GOAM_LIB_ROOT=" + p.libRoot_orEmpty}
+		} else if libInstallRoot != goRootLibDir {
+			// For example, a deps root
+			env = []string{"GOAM_LIB_ROOT=" + libInstallRoot}
+		}
+		err = p.runInstallCommand(p.localDir, env)
+	}
+	if err != nil {
+		return err
+	}
+
+	if p.reportToDashboard {
+		maybeReportToDashboard(p.repository.DashboardPath())
+	}
+
+	return nil
+}
+
+// Runs the installation command in directory 'dir',
+// and checks that the command actually installed the package
+func (p *remote_package_t) runInstallCommand(dir string, env []string) error {
+	var err error
+	exe := &Executable{name: p.installCmd[0]}
+	if env != nil {
+		err = exe.runWithEnv(p.installCmd, dir, env)
+	} else {
+		err = exe.runSimply(p.installCmd, dir, /*dontPrint*/ false)
+	}
+	if err != nil {
+		return err
+	}
+
+	return p.checkInstalled()
+}
+
+// Checks that all import paths provided by the package can be resolved
+func (p *remote_package_t) checkInstalled() error {
+	for _, importPath := range p.importPaths {
+		_, err := resolvePackage(importPath, /*test*/ false)
+		if err != nil {
+			return errors.New("remote package \"" + p.repository.Path() + "\"" +
+				" failed to provide the library \"" + importPath + "\"")
 		}
 	}
 
@@ -131,6 +613,10 @@ func (r *repository_github_t) KindString() string {
 	return "GitHub"
 }
 
+func (r *repository_github_t) KindName() string {
+	return "github"
+}
+
 func (r *repository_github_t) Path() string {
 	return r.repositoryPath
 }
@@ -139,49 +625,42 @@ func (r *repository_github_t) DashboardPath() string {
 	return "github.com/" + r.repositoryPath
 }
 
-var git_exe = &Executable{
-	name: "git",
+func (r *repository_github_t) Tools() []*Executable {
+	return []*Executable{git_exe}
 }
 
-func (r *repository_github_t) CloneOrUpdate() (string, bool, error) {
-	var err error
+func (r *repository_github_t) ProjectDir() string {
+	return pathutil.Join(remotePkgInstallRoot, "github.com", r.repositoryPath)
+}
 
-	user, project := pathutil.Split(r.repositoryPath)
-	user = pathutil.Clean(user)
+func (r *repository_github_t) CurrentRevision() (string, error) {
+	return gitCurrentRevision(r.repositoryPath, r.ProjectDir())
+}
 
-	cloneDir := pathutil.Join(remotePkgInstallRoot, "github.com", user)
-	projectDir := pathutil.Join(cloneDir, project)
+func (r *repository_github_t) ResolveRevision(revision string) (string, error) {
+	return gitResolveRevision(r.repositoryPath, r.ProjectDir(), revision)
+}
 
-	exe := git_exe
+func (r *repository_github_t) HasLocalModifications() (bool, error) {
+	return gitHasLocalModifications(r.repositoryPath, r.ProjectDir())
+}
 
-	var alreadyCloned = fileExists(projectDir)
-	if !alreadyCloned {
-		err = mkdirAll(cloneDir, 0777)
-		if err != nil {
-			return "", false, err
-		}
+func (r *repository_github_t) CommitsBehind(revision string) (int, error) {
+	return gitCommitsBehind(r.repositoryPath, r.ProjectDir(), revision)
+}
 
-		// Clone
-		args := []string{exe.name, "clone", "https://github.com/" + user + "/" + project + ".git"}
-		err = exe.runSimply(args, cloneDir, /*dontPrint*/ false)
-		if err != nil {
-			return "", false, err
-		}
-	} else {
-		// Download changes and update local files
-		var args []string
-		if *flag_verbose {
-			args = []string{exe.name, "pull"}
-		} else {
-			args = []string{exe.name, "pull", "-q"}
-		}
-		err = exe.runSimply(args, projectDir, /*dontPrint*/ false)
-		if err != nil {
-			return "", false, err
-		}
+func (r *repository_github_t) TrackedFiles() ([]string, error) {
+	return gitTrackedFiles(r.repositoryPath, r.ProjectDir())
+}
+
+func (r *repository_github_t) CloneOrUpdate(revision string) (string, bool, error) {
+	url := "https://github.com/" + r.repositoryPath + ".git"
+	err := gitCloneOrUpdate(r.repositoryPath, url, r.ProjectDir(), revision)
+	if err != nil {
+		return "", false, err
 	}
 
-	return projectDir, true, nil
+	return r.ProjectDir(), true, nil
 }
 
 // ======================
@@ -200,6 +679,10 @@ func (r *repository_bitbucket_t) KindString() string {
 	return "BitBucket"
 }
 
+func (r *repository_bitbucket_t) KindName() string {
+	return "bitbucket"
+}
+
 func (r *repository_bitbucket_t) Path() string {
 	return r.repositoryPath
 }
@@ -208,66 +691,92 @@ func (r *repository_bitbucket_t) DashboardPath() string {
 	return "bitbucket.org/" + r.repositoryPath
 }
 
-var hg_exe = &Executable{
-	name: "hg",
+func (r *repository_bitbucket_t) Tools() []*Executable {
+	return []*Executable{hg_exe}
 }
 
-func (r *repository_bitbucket_t) CloneOrUpdate() (string, bool, error) {
-	var err error
+func (r *repository_bitbucket_t) ProjectDir() string {
+	return pathutil.Join(remotePkgInstallRoot, "bitbucket.org", r.repositoryPath)
+}
 
-	user, project := pathutil.Split(r.repositoryPath)
-	user = pathutil.Clean(user)
+func (r *repository_bitbucket_t) CurrentRevision() (string, error) {
+	return hgCurrentRevision(r.repositoryPath, r.ProjectDir())
+}
 
-	cloneDir := pathutil.Join(remotePkgInstallRoot, "bitbucket.org", user)
-	projectDir := pathutil.Join(cloneDir, project)
+func (r *repository_bitbucket_t) ResolveRevision(revision string) (string, error) {
+	return hgResolveRevision(r.repositoryPath, r.ProjectDir(), revision)
+}
 
-	exe := hg_exe
+func (r *repository_bitbucket_t) HasLocalModifications() (bool, error) {
+	return hgHasLocalModifications(r.repositoryPath, r.ProjectDir())
+}
 
-	var alreadyCloned = fileExists(projectDir)
-	if !alreadyCloned {
-		err = mkdirAll(cloneDir, 0777)
-		if err != nil {
-			return "", false, err
-		}
+func (r *repository_bitbucket_t) CommitsBehind(revision string) (int, error) {
+	return hgCommitsBehind(r.repositoryPath, r.ProjectDir(), revision)
+}
 
-		// Clone
-		args := []string{exe.name, "clone", "https://bitbucket.org/" + user + "/" + project}
-		err = exe.runSimply(args, cloneDir, /*dontPrint*/ false)
-		if err != nil {
-			return "", false, err
-		}
-	} else {
-		// Download changes
-		var args []string
-		if *flag_verbose {
-			args = []string{exe.name, "pull"}
-		} else {
-			args = []string{exe.name, "pull", "-q"}
-		}
-		err = exe.runSimply(args, projectDir, /*dontPrint*/ false)
-		if err != nil {
-			return "", false, err
-		}
+func (r *repository_bitbucket_t) TrackedFiles() ([]string, error) {
+	return hgTrackedFiles(r.repositoryPath, r.ProjectDir())
+}
 
-		// Update local files
-		if *flag_verbose {
-			args = []string{exe.name, "update"}
-		} else {
-			args = []string{exe.name, "update", "-q"}
-		}
-		err = exe.runSimply(args, projectDir, /*dontPrint*/ false)
-		if err != nil {
-			return "", false, err
-		}
+func (r *repository_bitbucket_t) CloneOrUpdate(revision string) (string, bool, error) {
+	url := "https://bitbucket.org/" + r.repositoryPath
+	err := hgCloneOrUpdate(r.repositoryPath, url, r.ProjectDir(), revision)
+	if err != nil {
+		return "", false, err
 	}
 
-	return projectDir, true, nil
+	return r.ProjectDir(), true, nil
 }
 
 // =================
 // Utility functions
 // =================
 
+// Returns the repository kind denoted by the repository type used in config files
+func parseRepositoryKind(kindString string) (int, error) {
+	switch strings.ToLower(kindString) {
+	case "github":
+		return GITHUB, nil
+	case "bitbucket":
+		return BITBUCKET, nil
+	case "git":
+		return GIT, nil
+	case "hg":
+		return HG, nil
+	case "svn":
+		return SVN, nil
+	case "bzr":
+		return BZR, nil
+	case "local":
+		return LOCAL, nil
+	}
+
+	return -1, errors.New("\"" + kindString + "\" is an invalid repository type")
+}
+
+// Creates a new object conforming to the interface 'repository_t'
+func new_repository(kind int, repositoryPath string) repository_t {
+	switch kind {
+	case GITHUB:
+		return new_repository_github(repositoryPath)
+	case BITBUCKET:
+		return new_repository_bitbucket(repositoryPath)
+	case GIT:
+		return new_repository_git(repositoryPath)
+	case HG:
+		return new_repository_hg(repositoryPath)
+	case SVN:
+		return new_repository_svn(repositoryPath)
+	case BZR:
+		return new_repository_bzr(repositoryPath)
+	case LOCAL:
+		return new_repository_local(repositoryPath)
+	}
+
+	panic("invalid kind")
+}
+
 func checkRepositoryPath(kind int, path string) error {
 	switch kind {
 	case GITHUB:
@@ -289,6 +798,22 @@ func checkRepositoryPath(kind int, path string) error {
 			return errors.New("invalid BitBucket repository path (try without the \"bitbucket.org\" prefix)")
 		}
 
+	case GIT:
+		// Git also accepts scp-like URLs, such as "git@example.com:project.git"
+		if !strings.Contains(path, "://") && !strings.Contains(path, ":") {
+			return errors.New("invalid git repository URL \"" + path + "\" (try adding a prefix such as \"https://\" or \"file://\")")
+		}
+
+	case HG, SVN, BZR:
+		if !strings.Contains(path, "://") {
+			return errors.New("invalid repository URL \"" + path + "\" (try adding a prefix such as \"https://\" or \"file://\")")
+		}
+
+	case LOCAL:
+		if strings.Contains(path, "://") {
+			return errors.New("invalid local repository path \"" + path + "\" (try removing \"file://\" or similar prefixes)")
+		}
+
 	default:
 		panic("invalid kind")
 	}
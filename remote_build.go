@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"os"
+	pathutil "path"
+)
+
+// Returns whether GOAM can build the sources of the package in directory 'dir'
+func canBuildWithGoam(dir string) bool {
+	return fileExists(pathutil.Join(dir, configFileName)) || fileExists(pathutil.Join(dir, "Makefile"))
+}
+
+// Builds and installs the package from the sources in directory 'dir'
+// by booting the directory tree with the same flags and tools as the current project.
+// The installed packages are the targets of 'InstallPackage' in the config files,
+// or, if there are none, the import paths provided by the remote package.
+// Libraries are installed into 'libRoot_orEmpty', or into 'libInstallRoot' if it is empty.
+func (p *remote_package_t) buildWithGoam(dir string, libRoot_orEmpty string) (err error) {
+	if *flag_debug {
+		println("build with goam:", dir)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	// The libraries of the current project are available to the remote package
+	var parentLibSearchRoots []string
+	for _, root := range libSearchRoots() {
+		if !pathutil.IsAbs(root) {
+			root = pathutil.Join(cwd, root)
+		}
+		parentLibSearchRoots = append(parentLibSearchRoots, root)
+	}
+
+	// The remote package is a separate project,
+	// but it is installed into the roots of the current project
+	savedProject := project
+	savedSrcRoot := *flag_srcroot
+	savedLibInstallRoot := libInstallRoot
+	savedDepsRootFixed := depsRootFixed
+	project = new_project_state()
+	project.parentLibSearchRoots = parentLibSearchRoots
+	defer func() {
+		project = savedProject
+		*flag_srcroot = savedSrcRoot
+		libInstallRoot = savedLibInstallRoot
+		depsRootFixed = savedDepsRootFixed
+	}()
+
+	// The package is being built even if the current project is not
+	savedGenerateMissingFiles := generateMissingFiles
+	generateMissingFiles = true
+	defer func() { generateMissingFiles = savedGenerateMissingFiles }()
+
+	// Zero-config mode applies only to the current project,
+	// and remote packages are installed into the deps root of the current project
+	*flag_srcroot = ""
+	depsRootFixed = true
+	if len(libRoot_orEmpty) != 0 {
+		libInstallRoot = libRoot_orEmpty
+	}
+
+	err = os.Chdir(dir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err1 := os.Chdir(cwd)
+		if err == nil {
+			err = err1
+		}
+	}()
+
+	rootObject, err := boot( /*updateTests*/ false)
+	if err != nil {
+		return errors.New("remote package \"" + p.repository.Path() + "\": " + err.Error())
+	}
+
+	err = checkBuildTools(rootObject, /*install*/ true)
+	if err != nil {
+		return err
+	}
+
+	// Only packages are installed, executables and directories of the remote project
+	// are not installed into the directories of the current project
+	var cmds []installation_command_t
+	for _, cmd := range project.installationCommands {
+		if installPackage, isInstallPackage := cmd.(*install_package_t); isInstallPackage {
+			cmds = append(cmds, installPackage)
+		}
+	}
+	if len(cmds) == 0 {
+		for _, importPath := range p.importPaths {
+			cmds = append(cmds, new_installPackage(importPath))
+		}
+	}
+
+	for _, cmd := range cmds {
+		err = cmd.Install(rootObject)
+		if err != nil {
+			return errors.New("remote package \"" + p.repository.Path() + "\": " + err.Error())
+		}
+	}
+
+	return nil
+}
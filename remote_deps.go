@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	pathutil "path"
+	"strconv"
+	"strings"
+)
+
+// A remote package defined by the config files of another project,
+// as printed by "goam remote-packages"
+type remote_package_def_t struct {
+	kind           int
+	repositoryPath string
+	revision       string
+	importPaths    []string
+	installCmd     []string
+}
+
+// Returns GOAM's own executable, for running GOAM in other directories
+func goamExecutable() (*Executable, error) {
+	path := os.Args[0]
+	if strings.Contains(path, "/") && !pathutil.IsAbs(path) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		path = pathutil.Join(cwd, path)
+	}
+
+	exe := &Executable{name: path}
+	err := exe.lookup()
+	if err != nil {
+		return nil, err
+	}
+
+	return exe, nil
+}
+
+// Prints the remote packages defined by the config files, one per line.
+// The line comprises the following words enclosed in double-quotes:
+// the repository type, the repository, the revision, the import paths,
+// and the words of the installation command.
+func writeRemotePackages(w io.Writer) error {
+	for _, p := range project.remotePackages {
+		// Replacements are defined by the project being built, not by the remote package
+		repository := p.declaredRepository()
+		words := []string{
+			repository.KindName(),
+			repository.Path(),
+			p.revision,
+			strings.Join(p.importPaths, " "),
+		}
+		words = append(words, p.installCmd...)
+
+		quoted := make([]string, len(words))
+		for i, word := range words {
+			quoted[i] = strconv.Quote(word)
+		}
+
+		_, err := io.WriteString(w, strings.Join(quoted, " ")+"\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Evaluates the config files of the project in directory 'dir'
+// by running "goam remote-packages", and returns the remote packages
+// defined by the config files
+func listRemotePackages(dir string) ([]*remote_package_def_t, error) {
+	exe, err := goamExecutable()
+	if err != nil {
+		return nil, err
+	}
+
+	// Config files are evaluated with the same GOOS, GOARCH, compiler, options
+	// and replacements as the config files of the current project
+	args := []string{
+		exe.name,
+		"-conf-arch=" + *flag_arch,
+		"-conf-os=" + *flag_os,
+		"-goroot=" + goRoot,
+	}
+	if *flag_gcc {
+		args = append(args, "-gcc")
+	}
+	args = append(args, flag_options.args("D")...)
+	args = append(args, options_flag_t(project.replacements).args("replace")...)
+	args = append(args, "remote-packages")
+
+	if *flag_debug {
+		println("list remote packages:", dir)
+	}
+
+	stdout, stderr, err := exe.run(args, dir, /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return nil, errors.New("failed to evaluate the config files in \"" + dir + "\": " + strings.TrimSpace(stderr))
+	}
+
+	var definitions []*remote_package_def_t
+	for _, line := range strings.Split(stdout, "\n") {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		words, err := splitQuotedWords(line)
+		if (err != nil) || (len(words) < 4) {
+			return nil, errors.New("unexpected output of \"goam remote-packages\" in \"" + dir + "\": " + line)
+		}
+
+		kind, err := parseRepositoryKind(words[0])
+		if err != nil {
+			return nil, err
+		}
+
+		definitions = append(definitions, &remote_package_def_t{
+			kind:           kind,
+			repositoryPath: words[1],
+			revision:       words[2],
+			importPaths:    strings.Fields(words[3]),
+			installCmd:     words[4:],
+		})
+	}
+
+	return definitions, nil
+}
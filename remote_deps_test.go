@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	pathutil "path"
+	"strings"
+	"testing"
+)
+
+// Emulates "goam remote-packages" in a project whose config file defines
+// a remote package depending on the option "backend"
+const fakeGoamRemotePackages = `
+echo "$@" > "$(dirname "$0")/args.log"
+for arg in "$@"; do
+	if [ "$arg" = "-D=backend=sqlite" ]; then
+		echo '"git" "https://example.com/sqlite.git" "" "example.com/sqlite"'
+		exit 0
+	fi
+done
+echo '"git" "https://example.com/mysql.git" "" "example.com/mysql"'
+`
+
+// The config files of a remote package are evaluated with the options of the current project
+func TestListRemotePackagesForwardsOptions(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	writeTestScript(t, "bin/goam", fakeGoamRemotePackages)
+	savedArg0 := os.Args[0]
+	os.Args[0] = pathutil.Join(dir, "bin", "goam")
+	defer func() { os.Args[0] = savedArg0 }()
+
+	err := mkdirAll("remote", 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importPathsOf := func() string {
+		definitions, err := listRemotePackages(pathutil.Join(dir, "remote"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var importPaths []string
+		for _, def := range definitions {
+			importPaths = append(importPaths, def.importPaths...)
+		}
+		return strings.Join(importPaths, " ")
+	}
+
+	if importPaths := importPathsOf(); importPaths != "example.com/mysql" {
+		t.Errorf("unexpected remote packages without options: %s", importPaths)
+	}
+
+	flag_options["backend"] = "sqlite"
+	defer delete(flag_options, "backend")
+	savedGcc := *flag_gcc
+	*flag_gcc = true
+	defer func() { *flag_gcc = savedGcc }()
+	project.replacements["example.com/util"] = "/src/util"
+
+	if importPaths := importPathsOf(); importPaths != "example.com/sqlite" {
+		t.Errorf("unexpected remote packages with the option backend=sqlite: %s", importPaths)
+	}
+
+	args, err := ioutil.ReadFile(pathutil.Join(dir, "bin", "args.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, arg := range []string{"-gcc", "-D=backend=sqlite", "-replace=example.com/util=/src/util", "remote-packages"} {
+		if !strings.Contains(string(args), arg) {
+			t.Errorf("expected the argument %s, got: %s", arg, args)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	pathutil "path"
+	"sort"
+)
+
+// Returns the SHA-256 checksum of the files in directory 'dir'.
+// The files are hashed in the order of their paths: the path and the size of a file
+// followed by the contents of the file, or the path and the target of a symbolic link.
+// Directories in the list (such as git submodules) are skipped.
+func hashSourceTree(dir string, files []string) (string, error) {
+	sorted := make([]string, len(files))
+	copy(sorted, files)
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	for _, file := range sorted {
+		path := pathutil.Join(dir, file)
+
+		fileInfo, err := os.Lstat(path)
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case fileInfo.IsDir():
+			continue
+
+		case (fileInfo.Mode() & os.ModeSymlink) != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "%s\x00link\x00%s\x00", file, target)
+
+		default:
+			fmt.Fprintf(h, "%s\x00%d\x00", file, fileInfo.Size())
+
+			f, err := os.Open(path)
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Returns the checksum of the files under version control in the local copy of the package
+func (p *remote_package_t) sourceChecksum() (string, error) {
+	files, err := p.repository.TrackedFiles()
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", nil
+	}
+
+	return hashSourceTree(p.repository.ProjectDir(), files)
+}
+
+// Returns the checksum recorded in the lock file, or an empty string.
+// The checksum applies only to the commit returned by 'lockedCommit'.
+func (p *remote_package_t) lockedChecksum() string {
+	if len(p.lockedCommit()) == 0 {
+		return ""
+	}
+
+	return project.lockEntries[p.repository.Path()].checksum
+}
+
+// Checks that the sources in the local copy of the package match the checksum in the lock file,
+// provided that the local copy is at the commit recorded in the lock file
+func (p *remote_package_t) verifyChecksum() error {
+	lockedChecksum := p.lockedChecksum()
+	if len(lockedChecksum) == 0 {
+		return nil
+	}
+
+	currentCommit, err := p.repository.CurrentRevision()
+	if err != nil {
+		return err
+	}
+	if currentCommit != p.lockedCommit() {
+		return nil
+	}
+
+	checksum, err := p.sourceChecksum()
+	if err != nil {
+		return err
+	}
+
+	if *flag_debug {
+		println("checksum:", p.repository.Path(), checksum)
+	}
+
+	if checksum != lockedChecksum {
+		return errors.New("remote package \"" + p.repository.Path() + "\": CHECKSUM MISMATCH:" +
+			" the sources of revision " + currentCommit + " have the checksum " + checksum +
+			", but " + lockFileName + " records the checksum " + lockedChecksum +
+			" (the revision might have been rewritten in the remote repository, the repository" +
+			" might have been tampered with, or files in \"" + p.repository.ProjectDir() + "\" have been modified)")
+	}
+
+	return nil
+}
+
+// Verifies the checksums of the local copies of all remote packages
+// which have not been verified during the installation
+func verifyAllRemotePackages() error {
+	for _, remotePackage := range project.remotePackages {
+		if remotePackage.verified || remotePackage.isVendored() || !fileExists(remotePackage.repository.ProjectDir()) {
+			continue
+		}
+
+		err := lookupExecutables(remotePackage.repository.Tools())
+		if err != nil {
+			return err
+		}
+
+		err = remotePackage.verifyChecksum()
+		if err != nil {
+			return err
+		}
+		remotePackage.verified = true
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	pathutil "path"
+	"strings"
+	"testing"
+)
+
+func TestHashSourceTree(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	writeTestFile(t, pathutil.Join(dir, "a.go"), "package a\n")
+	writeTestFile(t, pathutil.Join(dir, "sub", "b.go"), "package b\n")
+
+	hash := func(files ...string) string {
+		checksum, err := hashSourceTree(dir, files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return checksum
+	}
+
+	checksum := hash("a.go", "sub/b.go")
+	if hash("sub/b.go", "a.go") != checksum {
+		t.Errorf("the checksum depends on the order of the files")
+	}
+	if hash("a.go", "sub", "sub/b.go") != checksum {
+		t.Errorf("the checksum depends on directories")
+	}
+	if hash("a.go") == checksum {
+		t.Errorf("the checksum does not depend on the list of files")
+	}
+
+	writeTestFile(t, pathutil.Join(dir, "a.go"), "package a\n\n")
+	if hash("a.go", "sub/b.go") == checksum {
+		t.Errorf("the checksum does not depend on the contents of the files")
+	}
+}
+
+// "goam install-deps" fails if the sources of a locked commit do not match the checksum
+func TestInstallDetectsChecksumMismatch(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	origin := pathutil.Join(dir, "origin")
+	createTestGitRepository(t, origin)
+	commitTestFile(t, origin, "foo.go", "package foo\n")
+	url := "file://" + origin
+
+	restartTestProcess(t, dir)
+	p := defineTestRemotePackage(t, url, "")
+	err := installTestDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := readTestLockFile(t)
+
+	expectMismatch := func(situation string) {
+		restartTestProcess(t, dir)
+		defineTestRemotePackage(t, url, "")
+		err := installTestDependencies()
+		if (err == nil) || !strings.Contains(err.Error(), "CHECKSUM MISMATCH") {
+			t.Errorf("%s: expected a checksum mismatch, got %v", situation, err)
+		}
+		if newLock := readTestLockFile(t); newLock != lock {
+			t.Errorf("%s: the lock file has changed from:\n%s\nto:\n%s", situation, lock, newLock)
+		}
+	}
+
+	// Modify a tracked file in the local copy of the installed package
+	localFile := pathutil.Join(p.repository.ProjectDir(), "foo.go")
+	err = ioutil.WriteFile(localFile, []byte("package foo\n\nfunc init() { panic(\"tampered\") }\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectMismatch("installed package")
+
+	// The package is installed again from the tampered local copy
+	err = os.Remove(pathutil.Join(libInstallRoot, "example.com", "foo.a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectMismatch("package installed again")
+
+	// The original sources match the checksum
+	runTestGit(t, p.repository.ProjectDir(), "checkout", "--", "foo.go")
+	restartTestProcess(t, dir)
+	defineTestRemotePackage(t, url, "")
+	err = installTestDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
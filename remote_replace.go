@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"os"
+	pathutil "path"
+	"runtime"
+	"strings"
+)
+
+// The directory containing the libraries built from local directories replacing remote packages,
+// relative to the root directory of the project. Each replaced package has its own library root
+// in "DIR/NAME/pkg/GOOS_GOARCH", so that the libraries built from a local directory are not used
+// after the replacement is removed, and they do not overwrite the libraries of the remote package.
+const replacementsDirName = ".goam-replace"
+
+// Replacements defined on the command-line via "-replace IMPORT_PATH=DIR"
+var flag_replacements = make(options_flag_t)
+
+// Adds the replacements defined on the command-line to 'replacements'
+func initReplacements() error {
+	if len(flag_replacements) == 0 {
+		return nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	for importPath, dir := range flag_replacements {
+		if len(dir) == 0 {
+			return errors.New("-replace " + importPath + "=: empty directory")
+		}
+		if !pathutil.IsAbs(dir) {
+			dir = pathutil.Join(cwd, dir)
+		}
+		dir = pathutil.Clean(dir)
+
+		// Values are made absolute, so that they do not depend on the current directory
+		flag_replacements[importPath] = dir
+		project.replacements[importPath] = dir
+	}
+
+	return nil
+}
+
+// Replaces the repository of the remote package by the local directory 'dir'.
+// The package is then installed from the directory, using its installation command
+// or GOAM itself, and the lock file entry of the original repository is kept as it is.
+func (p *remote_package_t) replace(dir string) error {
+	if p.originalRepository_orNil != nil {
+		if p.repository.Path() != dir {
+			return errors.New("repository \"" + p.originalRepository_orNil.Path() + "\" is replaced by multiple" +
+				" local directories: \"" + p.repository.Path() + "\", \"" + dir + "\"")
+		}
+		return nil
+	}
+
+	if *flag_debug {
+		println("replace:", p.repository.Path(), "-->", dir)
+	}
+
+	p.originalRepository_orNil = p.repository
+	p.repository = new_repository_local(dir)
+	return nil
+}
+
+// Replaces the remote package if some of its import paths have a replacement
+func (p *remote_package_t) applyReplacements() error {
+	for _, importPath := range p.importPaths {
+		if dir, replaced := project.replacements[importPath]; replaced {
+			err := p.replace(dir)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the repository as defined by the config files
+func (p *remote_package_t) declaredRepository() repository_t {
+	if p.originalRepository_orNil != nil {
+		return p.originalRepository_orNil
+	}
+
+	return p.repository
+}
+
+func (p *remote_package_t) isReplaced() bool {
+	return p.originalRepository_orNil != nil
+}
+
+// Returns the directory where the replaced package installs its libraries
+func (p *remote_package_t) replacementLibRoot() string {
+	name := strings.Map(func(c rune) rune {
+		if (('a' <= c) && (c <= 'z')) || (('A' <= c) && (c <= 'Z')) || (('0' <= c) && (c <= '9')) || (c == '.') || (c == '-') {
+			return c
+		}
+		return '_'
+	}, p.originalRepository_orNil.Path())
+
+	return pathutil.Join(replacementsDirName, name, "pkg", runtime.GOOS+"_"+runtime.GOARCH)
+}
+
+// Returns the library roots of the replaced packages which have been installed
+func replacementLibRoots() []string {
+	var roots []string
+	for _, remotePackage := range project.remotePackages {
+		if remotePackage.isReplaced() && fileExists(remotePackage.replacementLibRoot()) {
+			roots = append(roots, remotePackage.replacementLibRoot())
+		}
+	}
+
+	return roots
+}
+
+// Returns the remote package providing the import path if the package is replaced, or nil
+func replacedPackage(importPath string) *remote_package_t {
+	if remotePackage, exists := project.remotePackages_byImport[importPath]; exists && remotePackage.isReplaced() {
+		return remotePackage
+	}
+
+	return nil
+}
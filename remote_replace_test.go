@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	pathutil "path"
+	"strings"
+	"testing"
+)
+
+// The libraries built from the local directory are used only while the replacement is in effect
+func TestReplacedPackageLibraryRoot(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	origin := pathutil.Join(dir, "origin")
+	createTestGitRepository(t, origin)
+	commitTestFile(t, origin, "foo.go", "package foo\n")
+	url := "file://" + origin
+
+	localDir := pathutil.Join(dir, "local")
+	writeTestFile(t, pathutil.Join(localDir, "foo.go"), "package foo\n\nconst Fixed = true\n")
+
+	replace := func() *remote_package_t {
+		project.replacements["example.com/foo"] = localDir
+		p := defineTestRemotePackage(t, url, "")
+		if !p.isReplaced() {
+			t.Fatalf("the package is not replaced")
+		}
+		return p
+	}
+
+	// Install the replacement
+	restartTestProcess(t, dir)
+	p := replace()
+	err := installTestDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacementLib := pathutil.Join(p.replacementLibRoot(), "example.com", "foo.a")
+	upstreamLib := pathutil.Join(libInstallRoot, "example.com", "foo.a")
+	if !fileExists(replacementLib) {
+		t.Fatalf("the library has not been installed into %s", p.replacementLibRoot())
+	}
+	if fileExists(upstreamLib) {
+		t.Errorf("the replaced package has been installed into %s", libInstallRoot)
+	}
+	if roots := libSearchRoots(); (len(roots) == 0) || (roots[0] != p.replacementLibRoot()) {
+		t.Errorf("expected %s to be searched first, got %v", p.replacementLibRoot(), roots)
+	}
+
+	// After the replacement is removed, the remote package is installed
+	restartTestProcess(t, dir)
+	p = defineTestRemotePackage(t, url, "")
+	if !p.installationRequired() {
+		t.Errorf("expected the remote package to be installed after removing the replacement")
+	}
+	for _, root := range libSearchRoots() {
+		if strings.HasPrefix(root, replacementsDirName) {
+			t.Errorf("the library root %s of the removed replacement is searched", root)
+		}
+	}
+	err = installTestDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fileExists(upstreamLib) {
+		t.Fatalf("the library has not been installed into %s", libInstallRoot)
+	}
+
+	// A replaced package is resolved only from the library root of the replacement
+	restartTestProcess(t, dir)
+	p = replace()
+	_, err = resolvePackage("example.com/foo", /*test*/ false)
+	if err != nil {
+		t.Errorf("expected the package to be resolved from %s: %v", p.replacementLibRoot(), err)
+	}
+	err = os.Remove(replacementLib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = resolvePackage("example.com/foo", /*test*/ false)
+	if (err == nil) || !strings.Contains(err.Error(), "replaced by the local directory") {
+		t.Errorf("expected the package not to be resolved from %s: %v", libInstallRoot, err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// Prints the state of the local copies of the remote packages: the checked-out revision,
+// whether files have been modified, whether the revision matches the pinned revision
+// and the lock file, and how many commits the local copy is behind the remote repository.
+// The remote packages required by remote packages are included.
+func writeRemotePackagesStatus(w io.Writer) error {
+	err := readLockFile()
+	if err != nil {
+		return err
+	}
+
+	// The list of remote packages grows while searching for dependencies
+	for i := 0; i < len(project.remotePackages); i++ {
+		p := project.remotePackages[i]
+		repository := p.repository
+		projectDir := repository.ProjectDir()
+
+		fmt.Fprintf(w, "%s %s\n", p.declaredRepository().KindName(), p.declaredRepository().Path())
+		if p.isReplaced() {
+			fmt.Fprintf(w, "    replaced:   %s\n", repository.Path())
+		}
+		if len(p.revision) != 0 {
+			fmt.Fprintf(w, "    pinned:     %s\n", p.revision)
+		}
+		if p.isVendored() {
+			fmt.Fprintf(w, "    vendored:   %s\n", p.vendorPath())
+		}
+
+		if !fileExists(projectDir) {
+			fmt.Fprintf(w, "    local copy: %s (does not exist)\n", projectDir)
+			if entry, locked := p.lockEntry(); locked {
+				fmt.Fprintf(w, "    lock:       %s\n", entry.commit)
+			}
+			continue
+		}
+		fmt.Fprintf(w, "    local copy: %s\n", projectDir)
+
+		if repository.Kind() == LOCAL {
+			// A local directory has no revisions
+			continue
+		}
+
+		err = lookupExecutables(repository.Tools())
+		if err != nil {
+			return err
+		}
+
+		currentCommit, err := repository.CurrentRevision()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "    revision:   %s\n", currentCommit)
+
+		modified, err := repository.HasLocalModifications()
+		if err != nil {
+			return err
+		}
+		if modified {
+			fmt.Fprintf(w, "    modified:   yes\n")
+		} else {
+			fmt.Fprintf(w, "    modified:   no\n")
+		}
+
+		if len(p.revision) != 0 {
+			requestedCommit, err := repository.ResolveRevision(p.revision)
+			switch {
+			case err != nil:
+				fmt.Fprintf(w, "    at pin:     unknown (%s)\n", err)
+			case requestedCommit == currentCommit:
+				fmt.Fprintf(w, "    at pin:     yes\n")
+			default:
+				fmt.Fprintf(w, "    at pin:     no (%s resolves to %s)\n", p.revision, requestedCommit)
+			}
+		}
+
+		switch entry, locked := p.lockEntry(); {
+		case !locked:
+			fmt.Fprintf(w, "    lock:       not recorded in %s\n", lockFileName)
+		case entry.revision != p.revision:
+			fmt.Fprintf(w, "    lock:       entry is for revision %s, config pins %s\n",
+				revisionName(entry.revision), revisionName(p.revision))
+		case entry.commit == currentCommit:
+			fmt.Fprintf(w, "    lock:       matches %s\n", lockFileName)
+		default:
+			fmt.Fprintf(w, "    lock:       differs from %s (%s)\n", lockFileName, entry.commit)
+		}
+
+		if *flag_offline {
+			fmt.Fprintf(w, "    upstream:   unknown (offline mode)\n")
+		} else {
+			behind, err := repository.CommitsBehind(p.revision)
+			switch {
+			case err != nil:
+				fmt.Fprintf(w, "    upstream:   unknown (%s)\n", err)
+			case behind == 0:
+				fmt.Fprintf(w, "    upstream:   up to date\n")
+			case behind == 1:
+				fmt.Fprintf(w, "    upstream:   1 commit behind\n")
+			default:
+				fmt.Fprintf(w, "    upstream:   %d commits behind\n", behind)
+			}
+		}
+
+		p.localDir = projectDir
+		err = p.findDependencies()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the revision as printed by "goam deps status"
+func revisionName(revision string) string {
+	if len(revision) == 0 {
+		return "(none)"
+	}
+	return revision
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	pathutil "path"
+	"strings"
+	"testing"
+)
+
+func TestRemotePackagesStatusAfterChangingThePin(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	origin := pathutil.Join(dir, "origin")
+	createTestGitRepository(t, origin)
+	commit1 := commitTestFile(t, origin, "foo.go", "package foo\n")
+	runTestGit(t, origin, "tag", "v1")
+	commit2 := commitTestFile(t, origin, "foo.go", "package foo\n\nconst Version = 2\n")
+	url := "file://" + origin
+
+	restartTestProcess(t, dir)
+	defineTestRemotePackage(t, url, "v1")
+	err := installTestDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := func() string {
+		var buf bytes.Buffer
+		err := writeRemotePackagesStatus(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	restartTestProcess(t, dir)
+	defineTestRemotePackage(t, url, "v1")
+	s := status()
+	for _, line := range []string{"    at pin:     yes\n", "    lock:       matches " + lockFileName + "\n"} {
+		if !strings.Contains(s, line) {
+			t.Errorf("expected %q in the status:\n%s", line, s)
+		}
+	}
+
+	// The config file pins a different revision than the lock file
+	restartTestProcess(t, dir)
+	defineTestRemotePackage(t, url, "main")
+	s = status()
+	for _, line := range []string{
+		"    revision:   " + commit1 + "\n",
+		"    at pin:     no (main resolves to " + commit2 + ")\n",
+		"    lock:       entry is for revision v1, config pins main\n",
+	} {
+		if !strings.Contains(s, line) {
+			t.Errorf("expected %q in the status:\n%s", line, s)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	pathutil "path"
+	"strings"
+	"testing"
+)
+
+// The installation command of the remote packages defined by the tests.
+// It creates the library "example.com/foo.a" in $GOAM_LIB_ROOT.
+var testInstallCmd = []string{"sh", "-c", "mkdir -p \"$GOAM_LIB_ROOT/example.com\" && touch \"$GOAM_LIB_ROOT/example.com/foo.a\""}
+
+// Defines the remote package "example.com/foo" provided by the git repository
+func defineTestRemotePackage(t *testing.T, url, revision string) *remote_package_t {
+	p, err := defineRemotePackage([]string{"example.com/foo"}, GIT, url, revision, testInstallCmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+// Does the same as "goam install-deps" after the config files have been read
+func installTestDependencies() error {
+	err := installAllRemotePackages()
+	if err != nil {
+		return err
+	}
+
+	err = verifyAllRemotePackages()
+	if err != nil {
+		return err
+	}
+
+	return writeLockFile()
+}
+
+// Returns the contents of the lock file without the comment line
+func readTestLockFile(t *testing.T) string {
+	contents, err := ioutil.ReadFile(lockFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(contents), "\n") {
+		if (len(line) != 0) && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestInstallRemotePackageFromRevision(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	origin := pathutil.Join(dir, "origin")
+	createTestGitRepository(t, origin)
+	commit1 := commitTestFile(t, origin, "foo.go", "package foo\n")
+	runTestGit(t, origin, "tag", "v1")
+	commit2 := commitTestFile(t, origin, "foo.go", "package foo\n\nconst Version = 2\n")
+	url := "file://" + origin
+
+	currentCommit := func(p *remote_package_t) string {
+		commit, err := p.repository.CurrentRevision()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return commit
+	}
+
+	// Install the tag
+	restartTestProcess(t, dir)
+	p := defineTestRemotePackage(t, url, "v1")
+	err := installTestDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fileExists(pathutil.Join(libInstallRoot, "example.com", "foo.a")) {
+		t.Fatalf("the library has not been installed into %s", libInstallRoot)
+	}
+	if current := currentCommit(p); current != commit1 {
+		t.Errorf("expected revision %s, got %s", commit1, current)
+	}
+	lock := readTestLockFile(t)
+	if !strings.HasPrefix(lock, "git "+url+" "+commit1+" v1 "+checksumPrefix) {
+		t.Errorf("unexpected lock file:\n%s", lock)
+	}
+
+	// Nothing to do if the local copy is at the locked revision
+	restartTestProcess(t, dir)
+	p = defineTestRemotePackage(t, url, "v1")
+	if p.installationRequired() {
+		t.Errorf("the package is installed at the requested revision")
+	}
+	err = installTestDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.fetched {
+		t.Errorf("the package has been fetched again")
+	}
+	if newLock := readTestLockFile(t); newLock != lock {
+		t.Errorf("the lock file has changed from:\n%s\nto:\n%s", lock, newLock)
+	}
+
+	// Changing the revision installs the package again
+	restartTestProcess(t, dir)
+	p = defineTestRemotePackage(t, url, "main")
+	if !p.installationRequired() {
+		t.Errorf("expected the package to be installed again after changing the revision")
+	}
+	err = installTestDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current := currentCommit(p); current != commit2 {
+		t.Errorf("expected revision %s after changing the revision, got %s", commit2, current)
+	}
+	if lock = readTestLockFile(t); !strings.HasPrefix(lock, "git "+url+" "+commit2+" main "+checksumPrefix) {
+		t.Errorf("unexpected lock file after changing the revision:\n%s", lock)
+	}
+
+	// Without a lock file, the local copy is compared with the revision
+	err = os.Remove(lockFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restartTestProcess(t, dir)
+	p = defineTestRemotePackage(t, url, "v1")
+	if !p.installationRequired() {
+		t.Errorf("expected the package to be installed again, the local copy is not at v1")
+	}
+	err = installTestDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current := currentCommit(p); current != commit1 {
+		t.Errorf("expected revision %s, got %s", commit1, current)
+	}
+}
+
+// In offline mode, the existing local copy is used if it is at the requested revision
+func TestInstallRemotePackageOffline(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	origin := pathutil.Join(dir, "origin")
+	createTestGitRepository(t, origin)
+	commitTestFile(t, origin, "foo.go", "package foo\n")
+	runTestGit(t, origin, "tag", "v1")
+	commitTestFile(t, origin, "foo.go", "package foo\n\nconst Version = 2\n")
+	url := "file://" + origin
+
+	savedOffline := *flag_offline
+	defer func() { *flag_offline = savedOffline }()
+
+	*flag_offline = true
+	restartTestProcess(t, dir)
+	defineTestRemotePackage(t, url, "v1")
+	err := installTestDependencies()
+	if (err == nil) || !strings.Contains(err.Error(), "offline mode") {
+		t.Errorf("expected an error, the package has not been cloned yet: %v", err)
+	}
+
+	*flag_offline = false
+	restartTestProcess(t, dir)
+	defineTestRemotePackage(t, url, "v1")
+	err = installTestDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The library is missing, the package is installed from the local copy
+	*flag_offline = true
+	err = os.Remove(pathutil.Join(libInstallRoot, "example.com", "foo.a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	restartTestProcess(t, dir)
+	defineTestRemotePackage(t, url, "v1")
+	err = installTestDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The revision cannot be changed in offline mode
+	restartTestProcess(t, dir)
+	defineTestRemotePackage(t, url, "main")
+	err = installTestDependencies()
+	if (err == nil) || !strings.Contains(err.Error(), "offline mode") {
+		t.Errorf("expected an error, the revision cannot be checked out in offline mode: %v", err)
+	}
+}
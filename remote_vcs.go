@@ -0,0 +1,1035 @@
+package main
+
+import (
+	"errors"
+	"os"
+	pathutil "path"
+	"strconv"
+	"strings"
+)
+
+// A git repository specified by its URL
+type repository_git_t struct {
+	url string
+}
+
+// A Mercurial repository specified by its URL
+type repository_hg_t struct {
+	url string
+}
+
+// A Subversion repository specified by its URL
+type repository_svn_t struct {
+	url string
+}
+
+// A Bazaar branch specified by its URL
+type repository_bzr_t struct {
+	url string
+}
+
+// A directory in the local file system.
+// The installation command runs directly in the directory.
+type repository_local_t struct {
+	path string
+}
+
+var git_exe = &Executable{
+	name: "git",
+}
+
+var hg_exe = &Executable{
+	name: "hg",
+}
+
+var svn_exe = &Executable{
+	name: "svn",
+}
+
+var bzr_exe = &Executable{
+	name: "bzr",
+}
+
+// Returns the directory of the local copy of the repository specified by 'url'.
+// For example: "https://example.com/repos/foo.git" --> "$remotePkgInstallRoot/example.com/repos/foo"
+func urlProjectDir(url string) string {
+	path := url
+	if i := strings.Index(path, "://"); i != -1 {
+		if path[0:i] == "file" {
+			path = "file/" + path[i+len("://"):]
+		} else {
+			path = path[i+len("://"):]
+		}
+	} else if i := strings.Index(path, ":"); i != -1 {
+		// An scp-like git URL, such as "git@example.com:foo.git"
+		path = path[0:i] + "/" + path[i+1:]
+	}
+
+	// Strip the user name and the port number from the host
+	{
+		hostEnd := strings.Index(path, "/")
+		if hostEnd == -1 {
+			hostEnd = len(path)
+		}
+
+		host := path[0:hostEnd]
+		if i := strings.LastIndex(host, "@"); i != -1 {
+			host = host[i+1:]
+		}
+		if i := strings.Index(host, ":"); i != -1 {
+			host = host[0:i]
+		}
+
+		path = host + path[hostEnd:]
+	}
+
+	if strings.HasSuffix(path, ".git") {
+		path = path[0 : len(path)-len(".git")]
+	}
+
+	// Remove "." and ".." from the path, the result cannot escape 'remotePkgInstallRoot'
+	path = pathutil.Clean("/" + path)
+
+	return pathutil.Join(remotePkgInstallRoot, path)
+}
+
+// =================
+// git, hg, svn, bzr
+// =================
+
+func gitCurrentRevision(repositoryPath, projectDir string) (string, error) {
+	args := []string{git_exe.name, "rev-parse", "HEAD"}
+	stdout, _, err := git_exe.run(args, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return "", errors.New("repository \"" + repositoryPath + "\": failed to determine the current revision: " + err.Error())
+	}
+
+	return strings.TrimSpace(stdout), nil
+}
+
+// Clones the git repository into 'projectDir', or updates 'projectDir',
+// and checks out the revision (if the revision is a non-empty string)
+func gitCloneOrUpdate(repositoryPath, url, projectDir, revision string) error {
+	var err error
+
+	exe := git_exe
+
+	var alreadyCloned = fileExists(projectDir)
+	if alreadyCloned && (len(revision) == 0) {
+		// A revision from the lock file might have been checked out,
+		// in which case the local copy is not on a branch
+		_, _, err = exe.run([]string{exe.name, "symbolic-ref", "-q", "HEAD"}, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ true)
+		if err != nil {
+			revision, err = gitDefaultBranch(repositoryPath, projectDir)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !alreadyCloned {
+		cloneDir, project := pathutil.Split(projectDir)
+		err = mkdirAll(cloneDir, 0777)
+		if err != nil {
+			return err
+		}
+
+		// Clone
+		args := []string{exe.name, "clone", url, project}
+		err = exe.runSimply(args, cloneDir, /*dontPrint*/ false)
+		if err != nil {
+			return err
+		}
+	} else if len(revision) == 0 {
+		// Download changes and update local files
+		var args []string
+		if *flag_verbose {
+			args = []string{exe.name, "pull"}
+		} else {
+			args = []string{exe.name, "pull", "-q"}
+		}
+		err = exe.runSimply(args, projectDir, /*dontPrint*/ false)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Download changes without updating local files,
+		// the local copy might not be on a branch
+		args := []string{exe.name, "fetch", "-q", "--tags", "origin"}
+		err = exe.runSimply(args, projectDir, /*dontPrint*/ false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(revision) != 0 {
+		err = gitCheckout(repositoryPath, projectDir, revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns whether 'revision' names a commit in the local copy of the repository
+func gitRevisionExists(projectDir, revision string) bool {
+	args := []string{git_exe.name, "rev-parse", "-q", "--verify", revision + "^{commit}"}
+	_, _, err := git_exe.run(args, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ true)
+	return err == nil
+}
+
+// Returns the commit ID of the revision in the local copy.
+// A branch is resolved to the branch in the remote repository, as in 'gitCheckout'.
+func gitResolveRevision(repositoryPath, projectDir, revision string) (string, error) {
+	for _, name := range []string{"origin/" + revision, revision} {
+		args := []string{git_exe.name, "rev-parse", "-q", "--verify", name + "^{commit}"}
+		stdout, _, err := git_exe.run(args, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ false)
+		if err == nil {
+			return strings.TrimSpace(stdout), nil
+		}
+	}
+
+	return "", errors.New("repository \"" + repositoryPath + "\": revision \"" + revision + "\" does not exist in the local copy")
+}
+
+// Returns the name of the default branch of the remote repository
+func gitDefaultBranch(repositoryPath, projectDir string) (string, error) {
+	args := []string{git_exe.name, "rev-parse", "--abbrev-ref", "origin/HEAD"}
+	stdout, _, err := git_exe.run(args, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return "", errors.New("repository \"" + repositoryPath + "\": failed to determine the default branch: " + err.Error())
+	}
+
+	branch := strings.TrimSpace(stdout)
+	if !strings.HasPrefix(branch, "origin/") {
+		return "", errors.New("repository \"" + repositoryPath + "\": failed to determine the default branch")
+	}
+
+	return branch[len("origin/"):], nil
+}
+
+// Checks out a tag, branch or commit
+func gitCheckout(repositoryPath, projectDir, revision string) error {
+	exe := git_exe
+
+	var args []string
+	if gitRevisionExists(projectDir, "origin/"+revision) {
+		// A branch: follow the branch in the remote repository
+		args = []string{exe.name, "checkout", "-q", "-B", revision, "origin/" + revision}
+	} else if gitRevisionExists(projectDir, revision) {
+		// A tag or a commit
+		args = []string{exe.name, "checkout", "-q", revision}
+	} else {
+		return errors.New("repository \"" + repositoryPath + "\": revision \"" + revision + "\" does not exist")
+	}
+
+	return exe.runSimply(args, projectDir, /*dontPrint*/ false)
+}
+
+// Returns whether tracked files in the local copy have been modified
+func gitHasLocalModifications(repositoryPath, projectDir string) (bool, error) {
+	args := []string{git_exe.name, "status", "--porcelain", "--untracked-files=no"}
+	stdout, _, err := git_exe.run(args, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return false, errors.New("repository \"" + repositoryPath + "\": failed to determine local modifications: " + err.Error())
+	}
+
+	return len(strings.TrimSpace(stdout)) != 0, nil
+}
+
+// Downloads changes without updating local files, and returns the number of commits
+// in the revision (or in the default branch) which are missing in the checked-out commit
+func gitCommitsBehind(repositoryPath, projectDir, revision string) (int, error) {
+	var err error
+
+	args := []string{git_exe.name, "fetch", "-q", "--tags", "origin"}
+	err = git_exe.runSimply(args, projectDir, /*dontPrint*/ false)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(revision) == 0 {
+		revision, err = gitDefaultBranch(repositoryPath, projectDir)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var target string
+	if gitRevisionExists(projectDir, "origin/"+revision) {
+		target = "origin/" + revision
+	} else if gitRevisionExists(projectDir, revision) {
+		target = revision
+	} else {
+		return 0, errors.New("repository \"" + repositoryPath + "\": revision \"" + revision + "\" does not exist")
+	}
+
+	args = []string{git_exe.name, "rev-list", "HEAD.." + target}
+	stdout, _, err := git_exe.run(args, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return 0, err
+	}
+
+	// One commit ID per line
+	return len(strings.Fields(stdout)), nil
+}
+
+// Returns the files under version control in the local copy, relative to 'projectDir'
+func gitTrackedFiles(repositoryPath, projectDir string) ([]string, error) {
+	args := []string{git_exe.name, "ls-files", "-z"}
+	stdout, _, err := git_exe.run(args, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return nil, errors.New("repository \"" + repositoryPath + "\": failed to list files: " + err.Error())
+	}
+
+	var files []string
+	for _, file := range strings.Split(stdout, "\x00") {
+		if len(file) != 0 {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+func hgCurrentRevision(repositoryPath, projectDir string) (string, error) {
+	args := []string{hg_exe.name, "log", "-r", ".", "--template", "{node}"}
+	stdout, _, err := hg_exe.run(args, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return "", errors.New("repository \"" + repositoryPath + "\": failed to determine the current revision: " + err.Error())
+	}
+
+	return strings.TrimSpace(stdout), nil
+}
+
+// Returns the changeset ID of the revision (a tag, branch or changeset) in the local copy
+func hgResolveRevision(repositoryPath, projectDir, revision string) (string, error) {
+	args := []string{hg_exe.name, "log", "-r", revision, "--template", "{node}\n"}
+	stdout, _, err := hg_exe.run(args, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return "", errors.New("repository \"" + repositoryPath + "\": revision \"" + revision + "\" does not exist in the local copy")
+	}
+
+	// A branch name denotes the newest changeset of the branch
+	nodes := strings.Fields(stdout)
+	if len(nodes) == 0 {
+		return "", errors.New("repository \"" + repositoryPath + "\": revision \"" + revision + "\" does not exist in the local copy")
+	}
+
+	return nodes[len(nodes)-1], nil
+}
+
+// Clones the Mercurial repository into 'projectDir', or updates 'projectDir',
+// and updates the local files to the revision (or to the newest revision
+// of the current branch if the revision is an empty string)
+func hgCloneOrUpdate(repositoryPath, url, projectDir, revision string) error {
+	var err error
+
+	exe := hg_exe
+
+	var alreadyCloned = fileExists(projectDir)
+	if !alreadyCloned {
+		cloneDir, project := pathutil.Split(projectDir)
+		err = mkdirAll(cloneDir, 0777)
+		if err != nil {
+			return err
+		}
+
+		// Clone
+		args := []string{exe.name, "clone", url, project}
+		err = exe.runSimply(args, cloneDir, /*dontPrint*/ false)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Download changes
+		var args []string
+		if *flag_verbose {
+			args = []string{exe.name, "pull"}
+		} else {
+			args = []string{exe.name, "pull", "-q"}
+		}
+		err = exe.runSimply(args, projectDir, /*dontPrint*/ false)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Update local files
+	{
+		var args []string
+		if *flag_verbose {
+			args = []string{exe.name, "update"}
+		} else {
+			args = []string{exe.name, "update", "-q"}
+		}
+
+		if len(revision) != 0 {
+			// 'hg identify' fails if the revision (a tag, branch or changeset) does not exist
+			_, _, err = exe.run([]string{exe.name, "identify", "-r", revision}, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ true)
+			if err != nil {
+				return errors.New("repository \"" + repositoryPath + "\": revision \"" + revision + "\" does not exist")
+			}
+
+			args = append(args, "-r", revision)
+		} else if !alreadyCloned {
+			// 'hg clone' has already updated the local files
+			return nil
+		}
+
+		err = exe.runSimply(args, projectDir, /*dontPrint*/ false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns whether tracked files in the local copy have been modified
+func hgHasLocalModifications(repositoryPath, projectDir string) (bool, error) {
+	args := []string{hg_exe.name, "status", "-mard"}
+	stdout, _, err := hg_exe.run(args, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return false, errors.New("repository \"" + repositoryPath + "\": failed to determine local modifications: " + err.Error())
+	}
+
+	return len(strings.TrimSpace(stdout)) != 0, nil
+}
+
+// Returns the files under version control in the working directory, relative to 'projectDir'
+func hgTrackedFiles(repositoryPath, projectDir string) ([]string, error) {
+	args := []string{hg_exe.name, "manifest"}
+	stdout, _, err := hg_exe.run(args, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return nil, errors.New("repository \"" + repositoryPath + "\": failed to list files: " + err.Error())
+	}
+
+	var files []string
+	for _, file := range strings.Split(stdout, "\n") {
+		if len(file) != 0 {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+// Downloads changes without updating local files, and returns the number of changesets
+// in the revision (or in the current branch) which are missing in the working directory
+func hgCommitsBehind(repositoryPath, projectDir, revision string) (int, error) {
+	args := []string{hg_exe.name, "pull", "-q"}
+	err := hg_exe.runSimply(args, projectDir, /*dontPrint*/ false)
+	if err != nil {
+		return 0, err
+	}
+
+	target := "max(branch(.))"
+	if len(revision) != 0 {
+		target = revision
+	}
+
+	// The ancestors of the target which are not ancestors of the working directory
+	args = []string{hg_exe.name, "log", "-r", "::(" + target + ") - ::.", "--template", "{node}\n"}
+	stdout, _, err := hg_exe.run(args, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return 0, errors.New("repository \"" + repositoryPath + "\": failed to compare with revision \"" + target + "\": " + err.Error())
+	}
+
+	// One changeset ID per line
+	return len(strings.Fields(stdout)), nil
+}
+
+// ================
+// repository_git_t
+// ================
+
+func new_repository_git(url string) *repository_git_t {
+	return &repository_git_t{url}
+}
+
+func (r *repository_git_t) Kind() int {
+	return GIT
+}
+
+func (r *repository_git_t) KindString() string {
+	return "Git"
+}
+
+func (r *repository_git_t) KindName() string {
+	return "git"
+}
+
+func (r *repository_git_t) Path() string {
+	return r.url
+}
+
+func (r *repository_git_t) DashboardPath() string {
+	return r.url
+}
+
+func (r *repository_git_t) Tools() []*Executable {
+	return []*Executable{git_exe}
+}
+
+func (r *repository_git_t) ProjectDir() string {
+	return urlProjectDir(r.url)
+}
+
+func (r *repository_git_t) CurrentRevision() (string, error) {
+	return gitCurrentRevision(r.url, r.ProjectDir())
+}
+
+func (r *repository_git_t) ResolveRevision(revision string) (string, error) {
+	return gitResolveRevision(r.url, r.ProjectDir(), revision)
+}
+
+func (r *repository_git_t) HasLocalModifications() (bool, error) {
+	return gitHasLocalModifications(r.url, r.ProjectDir())
+}
+
+func (r *repository_git_t) CommitsBehind(revision string) (int, error) {
+	return gitCommitsBehind(r.url, r.ProjectDir(), revision)
+}
+
+func (r *repository_git_t) TrackedFiles() ([]string, error) {
+	return gitTrackedFiles(r.url, r.ProjectDir())
+}
+
+func (r *repository_git_t) CloneOrUpdate(revision string) (string, bool, error) {
+	err := gitCloneOrUpdate(r.url, r.url, r.ProjectDir(), revision)
+	if err != nil {
+		return "", false, err
+	}
+
+	// Arbitrary repositories might be private, they are not reported to the dashboard
+	return r.ProjectDir(), false, nil
+}
+
+// ===============
+// repository_hg_t
+// ===============
+
+func new_repository_hg(url string) *repository_hg_t {
+	return &repository_hg_t{url}
+}
+
+func (r *repository_hg_t) Kind() int {
+	return HG
+}
+
+func (r *repository_hg_t) KindString() string {
+	return "Mercurial"
+}
+
+func (r *repository_hg_t) KindName() string {
+	return "hg"
+}
+
+func (r *repository_hg_t) Path() string {
+	return r.url
+}
+
+func (r *repository_hg_t) DashboardPath() string {
+	return r.url
+}
+
+func (r *repository_hg_t) Tools() []*Executable {
+	return []*Executable{hg_exe}
+}
+
+func (r *repository_hg_t) ProjectDir() string {
+	return urlProjectDir(r.url)
+}
+
+func (r *repository_hg_t) CurrentRevision() (string, error) {
+	return hgCurrentRevision(r.url, r.ProjectDir())
+}
+
+func (r *repository_hg_t) ResolveRevision(revision string) (string, error) {
+	return hgResolveRevision(r.url, r.ProjectDir(), revision)
+}
+
+func (r *repository_hg_t) HasLocalModifications() (bool, error) {
+	return hgHasLocalModifications(r.url, r.ProjectDir())
+}
+
+func (r *repository_hg_t) CommitsBehind(revision string) (int, error) {
+	return hgCommitsBehind(r.url, r.ProjectDir(), revision)
+}
+
+func (r *repository_hg_t) TrackedFiles() ([]string, error) {
+	return hgTrackedFiles(r.url, r.ProjectDir())
+}
+
+func (r *repository_hg_t) CloneOrUpdate(revision string) (string, bool, error) {
+	err := hgCloneOrUpdate(r.url, r.url, r.ProjectDir(), revision)
+	if err != nil {
+		return "", false, err
+	}
+
+	return r.ProjectDir(), false, nil
+}
+
+// ================
+// repository_svn_t
+// ================
+
+func new_repository_svn(url string) *repository_svn_t {
+	return &repository_svn_t{url}
+}
+
+func (r *repository_svn_t) Kind() int {
+	return SVN
+}
+
+func (r *repository_svn_t) KindString() string {
+	return "Subversion"
+}
+
+func (r *repository_svn_t) KindName() string {
+	return "svn"
+}
+
+func (r *repository_svn_t) Path() string {
+	return r.url
+}
+
+func (r *repository_svn_t) DashboardPath() string {
+	return r.url
+}
+
+func (r *repository_svn_t) Tools() []*Executable {
+	return []*Executable{svn_exe}
+}
+
+func (r *repository_svn_t) ProjectDir() string {
+	return urlProjectDir(r.url)
+}
+
+// Returns the value of the field (such as "Revision:") in the output of "svn info"
+func svnInfoField(output, field string) (string, error) {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, field) {
+			return strings.TrimSpace(line[len(field):]), nil
+		}
+	}
+
+	return "", errors.New("unexpected output of \"svn info\"")
+}
+
+// Returns the revision number of the working copy
+func (r *repository_svn_t) CurrentRevision() (string, error) {
+	args := []string{svn_exe.name, "info"}
+	stdout, _, err := svn_exe.run(args, r.ProjectDir(), /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err == nil {
+		var revision string
+		revision, err = svnInfoField(stdout, "Revision:")
+		if err == nil {
+			return revision, nil
+		}
+	}
+
+	return "", errors.New("repository \"" + r.url + "\": failed to determine the current revision: " + err.Error())
+}
+
+// Returns the revision number of the revision (a number, or a keyword such as "HEAD")
+func (r *repository_svn_t) ResolveRevision(revision string) (string, error) {
+	args := []string{svn_exe.name, "info", "-r", revision}
+	stdout, _, err := svn_exe.run(args, r.ProjectDir(), /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err == nil {
+		var number string
+		number, err = svnInfoField(stdout, "Revision:")
+		if err == nil {
+			return number, nil
+		}
+	}
+
+	return "", errors.New("repository \"" + r.url + "\": failed to resolve revision \"" + revision + "\": " + err.Error())
+}
+
+func (r *repository_svn_t) HasLocalModifications() (bool, error) {
+	args := []string{svn_exe.name, "status", "-q"}
+	stdout, _, err := svn_exe.run(args, r.ProjectDir(), /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return false, errors.New("repository \"" + r.url + "\": failed to determine local modifications: " + err.Error())
+	}
+
+	return len(strings.TrimSpace(stdout)) != 0, nil
+}
+
+// Returns the number of commits between the working copy and the revision (or HEAD)
+func (r *repository_svn_t) CommitsBehind(revision string) (int, error) {
+	if len(revision) == 0 {
+		revision = "HEAD"
+	}
+
+	current, err := r.CurrentRevision()
+	if err != nil {
+		return 0, err
+	}
+
+	args := []string{svn_exe.name, "info", "-r", revision, r.url}
+	stdout, _, err := svn_exe.run(args, /*dir*/ "", /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return 0, errors.New("repository \"" + r.url + "\": revision \"" + revision + "\" does not exist")
+	}
+	target, err := svnInfoField(stdout, "Last Changed Rev:")
+	if err != nil {
+		return 0, err
+	}
+
+	currentNumber, err1 := strconv.Atoi(current)
+	targetNumber, err2 := strconv.Atoi(target)
+	if (err1 != nil) || (err2 != nil) {
+		return 0, errors.New("repository \"" + r.url + "\": invalid revision numbers " + current + " and " + target)
+	}
+	if targetNumber <= currentNumber {
+		return 0, nil
+	}
+
+	args = []string{svn_exe.name, "log", "-q", "-r", strconv.Itoa(currentNumber+1) + ":" + target, r.url}
+	stdout, _, err = svn_exe.run(args, /*dir*/ "", /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return 0, err
+	}
+
+	// Each commit is a line like "r123 | user | date", separated by lines of dashes
+	behind := 0
+	for _, line := range strings.Split(stdout, "\n") {
+		if strings.HasPrefix(line, "r") {
+			behind++
+		}
+	}
+
+	return behind, nil
+}
+
+// Parses the output of "svn info -R", which consists of blocks like
+// "Path: FILE", ..., "Node Kind: file", separated by empty lines
+func (r *repository_svn_t) TrackedFiles() ([]string, error) {
+	args := []string{svn_exe.name, "info", "-R"}
+	stdout, _, err := svn_exe.run(args, r.ProjectDir(), /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return nil, errors.New("repository \"" + r.url + "\": failed to list files: " + err.Error())
+	}
+
+	var files []string
+	for _, block := range strings.Split(stdout, "\n\n") {
+		path, err1 := svnInfoField(block, "Path:")
+		kind, err2 := svnInfoField(block, "Node Kind:")
+		if (err1 == nil) && (err2 == nil) && (kind == "file") {
+			files = append(files, path)
+		}
+	}
+
+	return files, nil
+}
+
+func (r *repository_svn_t) CloneOrUpdate(revision string) (string, bool, error) {
+	var err error
+
+	exe := svn_exe
+	projectDir := r.ProjectDir()
+
+	if len(revision) != 0 {
+		// 'svn info' fails if the revision does not exist
+		_, _, err = exe.run([]string{exe.name, "info", "-r", revision, r.url}, /*dir*/ "", /*in*/ "", /*mergeStdoutAndStderr*/ true)
+		if err != nil {
+			return "", false, errors.New("repository \"" + r.url + "\": revision \"" + revision + "\" does not exist")
+		}
+	}
+
+	var args []string
+	if !fileExists(projectDir) {
+		cloneDir, project := pathutil.Split(projectDir)
+		err = mkdirAll(cloneDir, 0777)
+		if err != nil {
+			return "", false, err
+		}
+
+		// Checkout
+		args = []string{exe.name, "checkout", "-q"}
+		if len(revision) != 0 {
+			args = append(args, "-r", revision)
+		}
+		args = append(args, r.url, project)
+		err = exe.runSimply(args, cloneDir, /*dontPrint*/ false)
+	} else {
+		// Update
+		args = []string{exe.name, "update", "-q"}
+		if len(revision) != 0 {
+			args = append(args, "-r", revision)
+		}
+		err = exe.runSimply(args, projectDir, /*dontPrint*/ false)
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return projectDir, false, nil
+}
+
+// ================
+// repository_bzr_t
+// ================
+
+func new_repository_bzr(url string) *repository_bzr_t {
+	return &repository_bzr_t{url}
+}
+
+func (r *repository_bzr_t) Kind() int {
+	return BZR
+}
+
+func (r *repository_bzr_t) KindString() string {
+	return "Bazaar"
+}
+
+func (r *repository_bzr_t) KindName() string {
+	return "bzr"
+}
+
+func (r *repository_bzr_t) Path() string {
+	return r.url
+}
+
+func (r *repository_bzr_t) DashboardPath() string {
+	return r.url
+}
+
+func (r *repository_bzr_t) Tools() []*Executable {
+	return []*Executable{bzr_exe}
+}
+
+func (r *repository_bzr_t) ProjectDir() string {
+	return urlProjectDir(r.url)
+}
+
+// Returns the revision ID of the working tree, in the format "revid:ID"
+func (r *repository_bzr_t) CurrentRevision() (string, error) {
+	args := []string{bzr_exe.name, "revision-info", "--tree"}
+	stdout, _, err := bzr_exe.run(args, r.ProjectDir(), /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err == nil {
+		// The output has the format: REVNO REVID
+		fields := strings.Fields(stdout)
+		if len(fields) == 2 {
+			return "revid:" + fields[1], nil
+		}
+		err = errors.New("unexpected output of \"bzr revision-info\"")
+	}
+
+	return "", errors.New("repository \"" + r.url + "\": failed to determine the current revision: " + err.Error())
+}
+
+// Returns the revision ID of the revision in the local branch, in the format "revid:ID"
+func (r *repository_bzr_t) ResolveRevision(revision string) (string, error) {
+	args := []string{bzr_exe.name, "revision-info", "-r", revision}
+	stdout, _, err := bzr_exe.run(args, r.ProjectDir(), /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err == nil {
+		// The output has the format: REVNO REVID
+		fields := strings.Fields(stdout)
+		if len(fields) == 2 {
+			return "revid:" + fields[1], nil
+		}
+		err = errors.New("unexpected output of \"bzr revision-info\"")
+	}
+
+	return "", errors.New("repository \"" + r.url + "\": failed to resolve revision \"" + revision + "\": " + err.Error())
+}
+
+func (r *repository_bzr_t) HasLocalModifications() (bool, error) {
+	args := []string{bzr_exe.name, "status", "-S"}
+	stdout, _, err := bzr_exe.run(args, r.ProjectDir(), /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return false, errors.New("repository \"" + r.url + "\": failed to determine local modifications: " + err.Error())
+	}
+
+	// Unknown files are marked with "?"
+	for _, line := range strings.Split(stdout, "\n") {
+		line = strings.TrimSpace(line)
+		if (len(line) != 0) && !strings.HasPrefix(line, "?") {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Returns the difference between the revision numbers of the revision
+// (or of the tip of the remote branch) and of the working tree
+func (r *repository_bzr_t) CommitsBehind(revision string) (int, error) {
+	exe := bzr_exe
+
+	args := []string{exe.name, "revision-info", "-d", r.url}
+	if len(revision) != 0 {
+		args = append(args, "-r", revision)
+	}
+	stdout, _, err := exe.run(args, /*dir*/ "", /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return 0, errors.New("repository \"" + r.url + "\": revision \"" + revision + "\" does not exist")
+	}
+	target := strings.Fields(stdout)
+
+	args = []string{exe.name, "revision-info", "--tree"}
+	stdout, _, err = exe.run(args, r.ProjectDir(), /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return 0, err
+	}
+	current := strings.Fields(stdout)
+
+	// The output has the format: REVNO REVID
+	if (len(target) != 2) || (len(current) != 2) {
+		return 0, errors.New("unexpected output of \"bzr revision-info\"")
+	}
+
+	// Dotted revision numbers of merged revisions cannot be compared
+	targetNumber, err1 := strconv.Atoi(target[0])
+	currentNumber, err2 := strconv.Atoi(current[0])
+	if (err1 != nil) || (err2 != nil) {
+		return 0, errors.New("repository \"" + r.url + "\": unable to compare revisions " + current[0] + " and " + target[0])
+	}
+	if targetNumber <= currentNumber {
+		return 0, nil
+	}
+
+	return targetNumber - currentNumber, nil
+}
+
+func (r *repository_bzr_t) TrackedFiles() ([]string, error) {
+	args := []string{bzr_exe.name, "ls", "-R", "--versioned", "--kind=file"}
+	stdout, _, err := bzr_exe.run(args, r.ProjectDir(), /*in*/ "", /*mergeStdoutAndStderr*/ false)
+	if err != nil {
+		return nil, errors.New("repository \"" + r.url + "\": failed to list files: " + err.Error())
+	}
+
+	var files []string
+	for _, file := range strings.Split(stdout, "\n") {
+		if len(file) != 0 {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+func (r *repository_bzr_t) CloneOrUpdate(revision string) (string, bool, error) {
+	var err error
+
+	exe := bzr_exe
+	projectDir := r.ProjectDir()
+
+	if !fileExists(projectDir) {
+		cloneDir, project := pathutil.Split(projectDir)
+		err = mkdirAll(cloneDir, 0777)
+		if err != nil {
+			return "", false, err
+		}
+
+		// Branch
+		args := []string{exe.name, "branch", "-q", r.url, project}
+		err = exe.runSimply(args, cloneDir, /*dontPrint*/ false)
+		if err != nil {
+			return "", false, err
+		}
+	} else {
+		// Download changes and update the working tree
+		args := []string{exe.name, "pull", "-q", "--overwrite"}
+		err = exe.runSimply(args, projectDir, /*dontPrint*/ false)
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	if len(revision) != 0 {
+		// 'bzr revision-info' fails if the revision does not exist
+		_, _, err = exe.run([]string{exe.name, "revision-info", "-r", revision}, projectDir, /*in*/ "", /*mergeStdoutAndStderr*/ true)
+		if err != nil {
+			return "", false, errors.New("repository \"" + r.url + "\": revision \"" + revision + "\" does not exist")
+		}
+
+		args := []string{exe.name, "update", "-q", "-r", revision}
+		err = exe.runSimply(args, projectDir, /*dontPrint*/ false)
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	return projectDir, false, nil
+}
+
+// ==================
+// repository_local_t
+// ==================
+
+func new_repository_local(path string) *repository_local_t {
+	return &repository_local_t{path}
+}
+
+func (r *repository_local_t) Kind() int {
+	return LOCAL
+}
+
+func (r *repository_local_t) KindString() string {
+	return "local"
+}
+
+func (r *repository_local_t) KindName() string {
+	return "local"
+}
+
+func (r *repository_local_t) Path() string {
+	return r.path
+}
+
+func (r *repository_local_t) DashboardPath() string {
+	return r.path
+}
+
+func (r *repository_local_t) Tools() []*Executable {
+	return nil
+}
+
+func (r *repository_local_t) ProjectDir() string {
+	return r.path
+}
+
+// A local directory has no revisions, thus it is not recorded in the lock file
+func (r *repository_local_t) CurrentRevision() (string, error) {
+	return "", nil
+}
+
+func (r *repository_local_t) ResolveRevision(revision string) (string, error) {
+	return "", errors.New("local repository \"" + r.path + "\": revisions are not supported")
+}
+
+// A local directory has no revisions to compare with
+func (r *repository_local_t) HasLocalModifications() (bool, error) {
+	return false, nil
+}
+
+func (r *repository_local_t) CommitsBehind(revision string) (int, error) {
+	return 0, nil
+}
+
+// A local directory is not recorded in the lock file, thus it has no checksum
+func (r *repository_local_t) TrackedFiles() ([]string, error) {
+	return nil, nil
+}
+
+func (r *repository_local_t) CloneOrUpdate(revision string) (string, bool, error) {
+	if len(revision) != 0 {
+		return "", false, errors.New("local repository \"" + r.path + "\": revisions are not supported")
+	}
+
+	fileInfo, err := os.Stat(r.path)
+	if err != nil {
+		return "", false, err
+	}
+	if !fileInfo.IsDir() {
+		return "", false, errors.New("local repository \"" + r.path + "\" is not a directory")
+	}
+
+	return r.path, false, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	pathutil "path"
+	"strings"
+	"testing"
+)
+
+func TestUrlProjectDir(t *testing.T) {
+	savedRoot := remotePkgInstallRoot
+	remotePkgInstallRoot = "/deps/src"
+	defer func() { remotePkgInstallRoot = savedRoot }()
+
+	tests := [][2]string{
+		{"https://example.com/repos/foo.git", "/deps/src/example.com/repos/foo"},
+		{"git@example.com:foo.git", "/deps/src/example.com/foo"},
+		{"ssh://user@example.com:2222/foo", "/deps/src/example.com/foo"},
+		{"file:///srv/git/foo.git", "/deps/src/file/srv/git/foo"},
+		{"https://example.com/../../foo", "/deps/src/foo"},
+	}
+
+	for _, test := range tests {
+		if dir := urlProjectDir(test[0]); dir != test[1] {
+			t.Errorf("urlProjectDir(%q): expected %q, got %q", test[0], test[1], dir)
+		}
+	}
+}
+
+func TestGitCloneOrUpdateRevision(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	origin := pathutil.Join(dir, "origin")
+	createTestGitRepository(t, origin)
+	commit1 := commitTestFile(t, origin, "foo.go", "package foo\n")
+	runTestGit(t, origin, "tag", "v1")
+	commit2 := commitTestFile(t, origin, "foo.go", "package foo\n\nconst Version = 2\n")
+
+	remotePkgInstallRoot = pathutil.Join(dir, "deps", "src")
+	repository := new_repository_git("file://" + origin)
+
+	// Clone a tag
+	projectDir, reportToDashboard, err := repository.CloneOrUpdate("v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if projectDir != pathutil.Join(remotePkgInstallRoot, "file", origin) {
+		t.Errorf("unexpected local copy %q", projectDir)
+	}
+	if reportToDashboard {
+		t.Errorf("git repositories must not be reported to the dashboard")
+	}
+	if current, _ := repository.CurrentRevision(); current != commit1 {
+		t.Errorf("expected revision %s after checking out v1, got %s", commit1, current)
+	}
+	if resolved, _ := repository.ResolveRevision("v1"); resolved != commit1 {
+		t.Errorf("expected v1 to resolve to %s, got %s", commit1, resolved)
+	}
+
+	behind, err := repository.CommitsBehind("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if behind != 1 {
+		t.Errorf("expected the local copy to be 1 commit behind main, got %d", behind)
+	}
+
+	// Follow a branch
+	_, _, err = repository.CloneOrUpdate("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := repository.CurrentRevision(); current != commit2 {
+		t.Errorf("expected revision %s after checking out main, got %s", commit2, current)
+	}
+
+	// A new commit in the remote branch is known after the next update
+	commit3 := commitTestFile(t, origin, "bar.go", "package foo\n")
+	if resolved, _ := repository.ResolveRevision("main"); resolved != commit2 {
+		t.Errorf("expected main to resolve to %s before updating, got %s", commit2, resolved)
+	}
+	_, _, err = repository.CloneOrUpdate("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := repository.CurrentRevision(); current != commit3 {
+		t.Errorf("expected revision %s after updating main, got %s", commit3, current)
+	}
+
+	// Check out a commit
+	_, _, err = repository.CloneOrUpdate(commit1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := repository.CurrentRevision(); current != commit1 {
+		t.Errorf("expected revision %s after checking out the commit, got %s", commit1, current)
+	}
+
+	// Return to the default branch
+	_, _, err = repository.CloneOrUpdate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := repository.CurrentRevision(); current != commit3 {
+		t.Errorf("expected revision %s on the default branch, got %s", commit3, current)
+	}
+
+	_, _, err = repository.CloneOrUpdate("no-such-revision")
+	if (err == nil) || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected an error for a revision which does not exist, got %v", err)
+	}
+}
+
+func TestGitTrackedFiles(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	origin := pathutil.Join(dir, "origin")
+	createTestGitRepository(t, origin)
+	commitTestFile(t, origin, "foo.go", "package foo\n")
+	commitTestFile(t, origin, "sub/bar.go", "package bar\n")
+	writeTestFile(t, pathutil.Join(origin, "untracked.go"), "package foo\n")
+
+	files, err := gitTrackedFiles(origin, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(files, " ") != "foo.go sub/bar.go" {
+		t.Errorf("expected tracked files [foo.go sub/bar.go], got %v", files)
+	}
+
+	modified, err := gitHasLocalModifications(origin, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modified {
+		t.Errorf("untracked files are not local modifications")
+	}
+
+	writeTestFile(t, pathutil.Join(origin, "foo.go"), "package foo // modified\n")
+	modified, err = gitHasLocalModifications(origin, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !modified {
+		t.Errorf("expected a local modification")
+	}
+}
+
+func TestLocalRepository(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	writeTestFile(t, "lib/foo.go", "package foo\n")
+	repository := new_repository_local(pathutil.Join(dir, "lib"))
+
+	projectDir, _, err := repository.CloneOrUpdate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if projectDir != pathutil.Join(dir, "lib") {
+		t.Errorf("expected the local directory to be used as it is, got %q", projectDir)
+	}
+
+	_, _, err = repository.CloneOrUpdate("v1")
+	if err == nil {
+		t.Errorf("expected an error, local repositories do not support revisions")
+	}
+
+	_, _, err = new_repository_local(pathutil.Join(dir, "lib", "foo.go")).CloneOrUpdate("")
+	if err == nil {
+		t.Errorf("expected an error, a file is not a local repository")
+	}
+}
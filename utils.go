@@ -1,7 +1,10 @@
 package main
 
 import (
+	"errors"
 	"os"
+	"strconv"
+	"strings"
 )
 
 func mkdirAll(path string, perm uint32) error {
@@ -15,3 +18,49 @@ func fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
+
+// Splits a string into space-separated words.
+// A word can be enclosed in double-quotes, as produced by 'strconv.Quote'.
+func splitQuotedWords(s string) ([]string, error) {
+	var words []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if len(s) == 0 {
+			break
+		}
+
+		var word string
+		if s[0] == '"' {
+			end := -1
+			for i := 1; i < len(s); i++ {
+				if s[i] == '\\' {
+					i++
+				} else if s[i] == '"' {
+					end = i + 1
+					break
+				}
+			}
+			if end == -1 {
+				return nil, errors.New("unterminated quoted string")
+			}
+
+			var err error
+			word, err = strconv.Unquote(s[0:end])
+			if err != nil {
+				return nil, errors.New("invalid quoted string " + s[0:end])
+			}
+			s = s[end:]
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end == -1 {
+				end = len(s)
+			}
+			word = s[0:end]
+			s = s[end:]
+		}
+
+		words = append(words, word)
+	}
+
+	return words, nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	pathutil "path"
+	"runtime"
+)
+
+// The directory containing vendored remote packages, relative to the root directory of the project.
+// Sources are in "vendor/src", libraries built from the sources are in "vendor/pkg/GOOS_GOARCH".
+const vendorDirName = "vendor"
+
+// Returns the directory where vendored remote packages install their libraries
+func vendorLibRoot() string {
+	return pathutil.Join(vendorDirName, "pkg", runtime.GOOS+"_"+runtime.GOARCH)
+}
+
+// Returns the directories searched for installed libraries before
+// the libraries of the Go installation. The compiler and the linker
+// are given the directories via the "-I" and "-L" options.
+// The libraries of replaced packages take precedence over all other libraries.
+func libSearchRoots() []string {
+	roots := replacementLibRoots()
+	if fileExists(vendorLibRoot()) {
+		roots = append(roots, vendorLibRoot())
+	}
+	if libInstallRoot != goRootLibDir {
+		roots = append(roots, libInstallRoot)
+	}
+
+	// A remote package built by GOAM can use the libraries of the project building it
+	for _, parentRoot := range project.parentLibSearchRoots {
+		duplicate := false
+		for _, root := range roots {
+			if root == parentRoot {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			roots = append(roots, parentRoot)
+		}
+	}
+
+	return roots
+}
+
+// Returns the directory containing the vendored sources of the remote package
+func (p *remote_package_t) vendorPath() string {
+	projectDir := p.repository.ProjectDir()
+	if relPath, isWithin := relativePath(remotePkgInstallRoot, projectDir); isWithin && (len(relPath) > 0) {
+		return pathutil.Join(vendorDirName, "src", relPath)
+	}
+
+	// A local repository outside of 'remotePkgInstallRoot' is identified by its absolute path,
+	// like the local copy of a "file://" URL (see 'urlProjectDir')
+	if !pathutil.IsAbs(projectDir) {
+		if cwd, err := os.Getwd(); err == nil {
+			projectDir = pathutil.Join(cwd, projectDir)
+		}
+	}
+	return pathutil.Join(vendorDirName, "src", "local", projectDir)
+}
+
+// The local directory replacing a package takes precedence over the vendored sources
+func (p *remote_package_t) isVendored() bool {
+	return !p.isReplaced() && fileExists(p.vendorPath())
+}
+
+// Returns the local copy of the repository. Unless in offline mode,
+// the local copy is created or updated to the revision in the lock file.
+func (p *remote_package_t) localCopy() (string, error) {
+	projectDir := p.repository.ProjectDir()
+
+	if *flag_offline {
+		if !fileExists(projectDir) {
+			return "", errors.New("remote package \"" + p.repository.Path() + "\" is not available" +
+				" in offline mode (\"" + projectDir + "\" does not exist)")
+		}
+		return projectDir, nil
+	}
+
+	revision := p.lockedCommit()
+	if len(revision) == 0 {
+		revision = p.revision
+	}
+
+	projectDir, _, err := p.repository.CloneOrUpdate(revision)
+	if err != nil {
+		return "", err
+	}
+	p.fetched = true
+
+	return projectDir, nil
+}
+
+// Copies the sources of all remote packages, including the remote packages
+// they depend on, into the vendor directory
+func vendorAllRemotePackages() error {
+	err := readLockFile()
+	if err != nil {
+		return err
+	}
+
+	if !*flag_offline {
+		var tools []*Executable
+		for _, remotePackage := range project.remotePackages {
+			tools = append(tools, remotePackage.repository.Tools()...)
+		}
+
+		err = lookupExecutables(tools)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The list of remote packages grows while searching for dependencies
+	for i := 0; i < len(project.remotePackages); i++ {
+		remotePackage := project.remotePackages[i]
+		if remotePackage.isReplaced() {
+			return errors.New("remote package \"" + remotePackage.originalRepository_orNil.Path() + "\"" +
+				" is replaced by the local directory \"" + remotePackage.repository.Path() + "\"," +
+				" the replacement has to be removed before vendoring")
+		}
+		fmt.Fprintf(os.Stdout, "Vendoring remote package \""+remotePackage.repository.Path()+"\"\n")
+
+		src, err := remotePackage.localCopy()
+		if err != nil {
+			return err
+		}
+
+		remotePackage.localDir = src
+		err = remotePackage.findDependencies()
+		if err != nil {
+			return err
+		}
+
+		dst := remotePackage.vendorPath()
+		if *flag_debug {
+			println("remove-all:", dst)
+		}
+		err = os.RemoveAll(dst)
+		if err != nil {
+			return err
+		}
+
+		err = copyTree(src, dst, isVersionControlDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns whether the file name is the name of a directory
+// with version control metadata, such as ".git"
+func isVersionControlDir(name string) bool {
+	switch name {
+	case ".git", ".hg", ".svn", ".bzr":
+		return true
+	}
+
+	return false
+}
+
+// Copies the directory 'src' to 'dst', except for files for which 'skip' returns true
+func copyTree(src, dst string, skip func(name string) bool) error {
+	fileInfo, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fileInfo.IsDir():
+		err = mkdirAll(dst, uint32(fileInfo.Mode().Perm()))
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		list, err := f.Readdir(-1)
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range list {
+			if skip(entry.Name()) {
+				continue
+			}
+
+			err = copyTree(pathutil.Join(src, entry.Name()), pathutil.Join(dst, entry.Name()), skip)
+			if err != nil {
+				return err
+			}
+		}
+
+	case (fileInfo.Mode() & os.ModeSymlink) != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		err = os.Symlink(target, dst)
+		if err != nil {
+			return err
+		}
+
+	default:
+		in, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileInfo.Mode().Perm())
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(out, in)
+		if err != nil {
+			out.Close()
+			return err
+		}
+
+		err = out.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	pathutil "path"
+	"testing"
+)
+
+// Local repositories with the same name are vendored into different directories
+func TestVendorPathOfLocalRepositories(t *testing.T) {
+	dir, leave := enterTempDir(t)
+	defer leave()
+
+	restartTestProcess(t, dir)
+	a, err := defineRemotePackage([]string{"a/util"}, LOCAL, "/home/user/a/util", "", testInstallCmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := defineRemotePackage([]string{"b/util"}, LOCAL, "b/util", "", testInstallCmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if path := a.vendorPath(); path != "vendor/src/local/home/user/a/util" {
+		t.Errorf("unexpected vendor path %s", path)
+	}
+	if path := b.vendorPath(); path != pathutil.Join("vendor/src/local", dir, "b/util") {
+		t.Errorf("unexpected vendor path %s", path)
+	}
+}